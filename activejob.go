@@ -0,0 +1,250 @@
+package goworker
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// activeJobResqueWrapper and activeJobSidekiqWrapper are
+	// the classes ActiveJob enqueues every job as, with the
+	// job itself serialized in the first argument.
+	activeJobResqueWrapper  = "ActiveJob::QueueAdapters::ResqueAdapter::JobWrapper"
+	activeJobSidekiqWrapper = "ActiveJob::QueueAdapters::SidekiqAdapter::JobWrapper"
+
+	// activeJobTimeFormat is the format of enqueued_at.
+	activeJobTimeFormat = "2006-01-02T15:04:05.000000000Z"
+)
+
+var errorActiveJobEnvelope = errors.New("the ActiveJob wrapper has no job_class and arguments")
+
+// GlobalID is a Rails GlobalID URI, such as
+// gid://app/User/1, which ActiveJob passes in place of an
+// ActiveRecord model.
+type GlobalID string
+
+// App returns the name of the Rails application.
+func (g GlobalID) App() string {
+	uri, err := url.Parse(string(g))
+	if err != nil {
+		return ""
+	}
+	return uri.Host
+}
+
+// Model returns the class name of the record, such as User.
+func (g GlobalID) Model() string {
+	model, _ := g.split()
+	return model
+}
+
+// ModelID returns the ID of the record.
+func (g GlobalID) ModelID() string {
+	_, id := g.split()
+	return id
+}
+
+func (g GlobalID) split() (string, string) {
+	uri, err := url.Parse(string(g))
+	if err != nil {
+		return "", ""
+	}
+	parts := strings.SplitN(strings.TrimPrefix(uri.Path, "/"), "/", 2)
+	if len(parts) != 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// isActiveJobWrapper reports whether class is one of the
+// classes ActiveJob wraps jobs in.
+func isActiveJobWrapper(class string) bool {
+	return class == activeJobResqueWrapper || class == activeJobSidekiqWrapper
+}
+
+// activeJobEnvelope returns the serialized ActiveJob job
+// wrapped in job, or nil if job is not an ActiveJob job.
+func activeJobEnvelope(job *Job) map[string]interface{} {
+	if !isActiveJobWrapper(job.Payload.Class) || len(job.Payload.Args) == 0 {
+		return nil
+	}
+	envelope, _ := job.Payload.Args[0].(map[string]interface{})
+	return envelope
+}
+
+// jobClass returns the class job is dispatched by: the
+// job_class of an ActiveJob job, or else its payload class.
+func jobClass(job *Job) string {
+	if envelope := activeJobEnvelope(job); envelope != nil {
+		if class, ok := envelope["job_class"].(string); ok {
+			return class
+		}
+	}
+	return job.Payload.Class
+}
+
+// lookupJobFunc returns the worker function registered for
+// the class of job. The function for an ActiveJob job is
+// called with the job unwrapped.
+func lookupJobFunc(job *Job) (jobFunc, bool) {
+	if !isActiveJobWrapper(job.Payload.Class) {
+		return workers.Get(job.Payload.Class)
+	}
+
+	worker, ok := workers.Get(jobClass(job))
+	if !ok {
+		return nil, false
+	}
+	return func(ctx context.Context, job *Job) error {
+		unwrapped, err := unwrapActiveJob(job)
+		if err != nil {
+			return err
+		}
+		return worker(context.WithValue(ctx, jobContextKey{}, unwrapped), unwrapped)
+	}, true
+}
+
+// unwrapActiveJob returns the job wrapped in an ActiveJob
+// job, with its job_class as the class and its arguments
+// deserialized. It keeps the ID, attempt and metadata of the
+// wrapper, by which goworker tracks the job.
+func unwrapActiveJob(job *Job) (*Job, error) {
+	envelope := activeJobEnvelope(job)
+	class, _ := envelope["job_class"].(string)
+	arguments, ok := envelope["arguments"].([]interface{})
+	if class == "" || !ok {
+		return nil, errorActiveJobEnvelope
+	}
+
+	unwrapped := &Job{Queue: job.Queue, Payload: job.Payload}
+	unwrapped.Payload.Class = class
+	unwrapped.Payload.Args = deserializeActiveJobArgs(arguments)
+	return unwrapped, nil
+}
+
+// deserializeActiveJobArgs reverses ActiveJob's argument
+// serialization. GlobalIDs become GlobalID values, values of
+// custom serializers such as symbols and times become their
+// serialized values, and the reserved _aj_ keys are removed
+// from hashes.
+func deserializeActiveJobArgs(args []interface{}) []interface{} {
+	deserialized := make([]interface{}, len(args))
+	for i, arg := range args {
+		deserialized[i] = deserializeActiveJobArg(arg)
+	}
+	return deserialized
+}
+
+func deserializeActiveJobArg(arg interface{}) interface{} {
+	switch arg := arg.(type) {
+	case []interface{}:
+		return deserializeActiveJobArgs(arg)
+	case map[string]interface{}:
+		if gid, ok := arg["_aj_globalid"].(string); ok && len(arg) == 1 {
+			return GlobalID(gid)
+		}
+		if _, ok := arg["_aj_serialized"]; ok {
+			if value, ok := arg["value"]; ok {
+				return deserializeActiveJobArg(value)
+			}
+		}
+		hash := make(map[string]interface{}, len(arg))
+		for key, value := range arg {
+			if !strings.HasPrefix(key, "_aj_") {
+				hash[key] = deserializeActiveJobArg(value)
+			}
+		}
+		return hash
+	}
+	return arg
+}
+
+// serializeActiveJobArgs serializes args as ActiveJob does,
+// so that Ruby deserializes GlobalIDs to their records and
+// hashes to hashes with string keys.
+func serializeActiveJobArgs(args []interface{}) []interface{} {
+	serialized := make([]interface{}, len(args))
+	for i, arg := range args {
+		serialized[i] = serializeActiveJobArg(arg)
+	}
+	return serialized
+}
+
+func serializeActiveJobArg(arg interface{}) interface{} {
+	switch arg := arg.(type) {
+	case GlobalID:
+		return map[string]interface{}{"_aj_globalid": string(arg)}
+	case []interface{}:
+		return serializeActiveJobArgs(arg)
+	case map[string]interface{}:
+		hash := make(map[string]interface{}, len(arg)+1)
+		for key, value := range arg {
+			hash[key] = serializeActiveJobArg(value)
+		}
+		hash["_aj_symbol_keys"] = []interface{}{}
+		return hash
+	}
+	return arg
+}
+
+// EnqueueActiveJob enqueues job for a Rails application
+// using ActiveJob, wrapped as ActiveJob's Resque adapter
+// does, or its Sidekiq adapter for a Sidekiq queue. The
+// payload class is the ActiveJob job class and the args are
+// its arguments, which may include GlobalIDs. The payload of
+// job is given the ID and enqueued time of the wrapper.
+func EnqueueActiveJob(job *Job) error {
+	if err := Init(); err != nil {
+		return err
+	}
+	if err := job.Payload.stamp(); err != nil {
+		return err
+	}
+	jobID, err := newUUID()
+	if err != nil {
+		return err
+	}
+
+	wrapper := activeJobResqueWrapper
+	if isSidekiqQueue(job.Queue) {
+		wrapper = activeJobSidekiqWrapper
+	}
+	args := job.Payload.Args
+	if args == nil {
+		args = []interface{}{}
+	}
+
+	wrapped := &Job{Queue: job.Queue, Payload: job.Payload}
+	wrapped.Payload.Class = wrapper
+	wrapped.Payload.Args = []interface{}{map[string]interface{}{
+		"job_class":            job.Payload.Class,
+		"job_id":               jobID,
+		"provider_job_id":      nil,
+		"queue_name":           job.Queue,
+		"priority":             nil,
+		"arguments":            serializeActiveJobArgs(args),
+		"executions":           0,
+		"exception_executions": map[string]interface{}{},
+		"locale":               "en",
+		"timezone":             "UTC",
+		"enqueued_at":          job.Payload.EnqueuedTime().UTC().Format(activeJobTimeFormat),
+	}}
+	return Enqueue(wrapped)
+}
+
+// newUUID returns a random version 4 UUID, the form of
+// ActiveJob job IDs.
+func newUUID() (string, error) {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	buffer[6] = buffer[6]&0x0f | 0x40
+	buffer[8] = buffer[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", buffer[0:4], buffer[4:6], buffer[6:8], buffer[8:10], buffer[10:]), nil
+}
@@ -0,0 +1,109 @@
+package goworker
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+var deserializeActiveJobArgTests = []struct {
+	arg      interface{}
+	expected interface{}
+}{
+	{
+		"plain",
+		"plain",
+	},
+	{
+		map[string]interface{}{"_aj_globalid": "gid://shop/User/42"},
+		GlobalID("gid://shop/User/42"),
+	},
+	{
+		map[string]interface{}{"a": 1.0, "_aj_symbol_keys": []interface{}{"a"}},
+		map[string]interface{}{"a": 1.0},
+	},
+	{
+		map[string]interface{}{"b": []interface{}{map[string]interface{}{"_aj_globalid": "gid://shop/Post/7"}}, "_aj_ruby2_keywords": []interface{}{"b"}},
+		map[string]interface{}{"b": []interface{}{GlobalID("gid://shop/Post/7")}},
+	},
+	{
+		map[string]interface{}{"_aj_serialized": "ActiveJob::Serializers::SymbolSerializer", "value": "pending"},
+		"pending",
+	},
+}
+
+func TestDeserializeActiveJobArg(t *testing.T) {
+	for _, tt := range deserializeActiveJobArgTests {
+		actual := deserializeActiveJobArg(tt.arg)
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("deserializeActiveJobArg(%#v): expected %#v, actual %#v", tt.arg, tt.expected, actual)
+		}
+	}
+}
+
+func TestGlobalID(t *testing.T) {
+	gid := GlobalID("gid://shop/Admin::User/a%2Fb")
+	if gid.App() != "shop" || gid.Model() != "Admin::User" || gid.ModelID() != "a/b" {
+		t.Errorf("GlobalID(%q): unexpected parts %q %q %q", gid, gid.App(), gid.Model(), gid.ModelID())
+	}
+}
+
+func TestEnqueueActiveJob(t *testing.T) {
+	workerSettings.Queues = []string{"activeJobQueue"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+	conn.Do("DEL", queueKey("activeJobQueue"))
+
+	args := []interface{}{GlobalID("gid://shop/User/42"), map[string]interface{}{"notify": true}}
+	job := &Job{Queue: "activeJobQueue", Payload: Payload{Class: "WelcomeJob", Args: args}}
+	if err := EnqueueActiveJob(job); err != nil {
+		t.Fatalf("EnqueueActiveJob: error %s", err)
+	}
+
+	popped := popTestJob(t, conn, "activeJobQueue")
+	if popped == nil || popped.Payload.Class != activeJobResqueWrapper || popped.Payload.ID != job.Payload.ID {
+		t.Fatalf("EnqueueActiveJob: unexpected job %+v", popped)
+	}
+	envelope := activeJobEnvelope(popped)
+	for _, key := range []string{"job_class", "job_id", "queue_name", "arguments", "executions", "enqueued_at"} {
+		if _, ok := envelope[key]; !ok {
+			t.Errorf("EnqueueActiveJob: expected %q in the envelope %v", key, envelope)
+		}
+	}
+
+	var received *Job
+	var fromContext *Job
+	workers.Add("WelcomeJob", func(ctx context.Context, job *Job) error {
+		received = job
+		fromContext, _ = JobFromContext(ctx)
+		return nil
+	})
+	defer delete(workers.workers, "WelcomeJob")
+
+	jobFunc, ok := lookupJobFunc(popped)
+	if !ok {
+		t.Fatalf("lookupJobFunc: expected the function registered for WelcomeJob")
+	}
+	if err := jobFunc(newJobContext(popped), popped); err != nil {
+		t.Fatalf("jobFunc: error %s", err)
+	}
+	if received == nil || received.Payload.Class != "WelcomeJob" || received != fromContext {
+		t.Fatalf("jobFunc: expected the unwrapped job, actual %+v", received)
+	}
+	if !reflect.DeepEqual(received.Payload.Args, args) {
+		t.Errorf("jobFunc: expected args %#v, actual %#v", args, received.Payload.Args)
+	}
+
+	if _, ok := lookupJobFunc(&Job{Payload: Payload{Class: activeJobResqueWrapper, Args: []interface{}{map[string]interface{}{"job_class": "MissingJob"}}}}); ok {
+		t.Errorf("lookupJobFunc: expected no function for MissingJob")
+	}
+}
@@ -0,0 +1,295 @@
+package goworker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	errorEmptyBatch    = errors.New("a batch needs at least one job")
+	errorBatchNotFound = errors.New("no batch with that ID")
+)
+
+// batchTTL is how long batch state is kept in Redis after
+// the batch was created.
+const batchTTL = 7 * 24 * time.Hour
+
+// pushStoredJobLua defines pushStoredJob, for the Lua
+// scripts that push jobs kept in Redis as a storedJob. The
+// queue is added to queuesKey unless the job names its own
+// queues set.
+const pushStoredJobLua = priorityScoreLua + `
+local function pushStoredJob(job, queuesKey)
+	if job.priority then
+		redis.call('ZADD', job.key, priorityScore(job.seq, job.score), job.payload)
+	elseif job.lpush then
+		redis.call('LPUSH', job.key, job.payload)
+	else
+		redis.call('RPUSH', job.key, job.payload)
+	end
+	redis.call('SADD', job.queues or queuesKey, job.queue)
+end
+`
+
+// finishBatchScript records that a job of a batch finished.
+// A job is counted once, however many times it runs. The
+// worker finishing the last job pushes the callback jobs in
+// the same script, so they are enqueued exactly once.
+var finishBatchScript = redis.NewScript(2, pushStoredJobLua+`
+if redis.call('SREM', KEYS[2], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], ARGV[2], 1)
+if redis.call('HINCRBY', KEYS[1], 'pending', -1) > 0 then
+	return 0
+end
+if redis.call('HSETNX', KEYS[1], 'fired', 1) == 0 then
+	return 0
+end
+local callbacks = {redis.call('HGET', KEYS[1], 'on_complete')}
+if tonumber(redis.call('HGET', KEYS[1], 'failed')) == 0 then
+	table.insert(callbacks, redis.call('HGET', KEYS[1], 'on_success'))
+end
+local fired = 0
+for _, callback in ipairs(callbacks) do
+	if callback then
+		pushStoredJob(cjson.decode(callback), ARGV[3])
+		fired = fired + 1
+	end
+end
+return fired
+`)
+
+// storedJob is a job kept in Redis, as a batch callback or a
+// later stage of a workflow, in the form the script that
+// enqueues it pushes it.
+type storedJob struct {
+	ID       string `json:"id"`
+	Queue    string `json:"queue"`
+	Key      string `json:"key"`
+	Priority bool   `json:"priority"`
+	Seq      string `json:"seq,omitempty"`
+	LPush    bool   `json:"lpush,omitempty"`
+	Queues   string `json:"queues,omitempty"`
+	Score    int    `json:"score"`
+	Payload  string `json:"payload"`
+}
+
+// newStoredJob stamps job and returns it as a storedJob.
+func newStoredJob(job *Job) (*storedJob, error) {
+	if err := job.Payload.stamp(); err != nil {
+		return nil, err
+	}
+
+	payload, err := encodeJob(job)
+	if err != nil {
+		return nil, err
+	}
+	command, _ := pushCommand(job.Queue, &job.Payload, payload)
+	stored := &storedJob{
+		ID:       job.Payload.ID,
+		Queue:    job.Queue,
+		Key:      queueKey(job.Queue),
+		Priority: command == "EVAL",
+		LPush:    command == "LPUSH",
+		Queues:   queuesKey(job.Queue),
+		Score:    job.Payload.Priority,
+		Payload:  string(payload),
+	}
+	if stored.Priority {
+		stored.Seq = prioritySeqKey(job.Queue)
+	}
+	return stored, nil
+}
+
+// Batch tracks a set of related jobs and enqueues callback
+// jobs once all of them have finished: OnComplete whether
+// or not they succeeded, and OnSuccess only if none failed.
+// Each job counts once, when it first finishes, so a failed
+// job retried from the failed list does not change the
+// outcome. Callback jobs can find the batch ID under the
+// batch_id key of their Payload.Meta.
+type Batch struct {
+	OnComplete *Job
+	OnSuccess  *Job
+	jobs       []*Job
+}
+
+// BatchState is the progress of a batch.
+type BatchState struct {
+	ID        string
+	Total     int
+	Pending   int
+	Succeeded int
+	Failed    int
+}
+
+// NewBatch returns an empty batch.
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Add adds jobs to the batch.
+func (b *Batch) Add(jobs ...*Job) *Batch {
+	b.jobs = append(b.jobs, jobs...)
+	return b
+}
+
+// Enqueue stores the batch in Redis, enqueues its jobs, and
+// returns the batch ID. Jobs that cannot be enqueued are
+// counted as failed, and the first such error is returned
+// along with the ID.
+func (b *Batch) Enqueue() (string, error) {
+	if len(b.jobs) == 0 {
+		return "", errorEmptyBatch
+	}
+
+	if err := Init(); err != nil {
+		return "", err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return "", err
+	}
+
+	fields := []interface{}{batchKey(id), "total", len(b.jobs), "pending", len(b.jobs), "succeeded", 0, "failed", 0}
+	for _, callback := range []struct {
+		field string
+		job   *Job
+	}{{"on_complete", b.OnComplete}, {"on_success", b.OnSuccess}} {
+		if callback.job == nil {
+			continue
+		}
+		buffer, err := newBatchCallback(id, callback.job)
+		if err != nil {
+			return "", err
+		}
+		fields = append(fields, callback.field, buffer)
+	}
+
+	members := []interface{}{batchJobsKey(id)}
+	for _, job := range b.jobs {
+		if job.Payload.Meta == nil {
+			job.Payload.Meta = make(map[string]interface{})
+		}
+		job.Payload.Meta["batch_id"] = id
+		if err := job.Payload.stamp(); err != nil {
+			return "", err
+		}
+		members = append(members, job.Payload.ID)
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		logger.Criticalf("Error on getting connection on batch enqueue")
+		return "", err
+	}
+	ttl := int(batchTTL / time.Second)
+	conn.Send("HSET", fields...)
+	conn.Send("EXPIRE", batchKey(id), ttl)
+	conn.Send("SADD", members...)
+	conn.Send("EXPIRE", batchJobsKey(id), ttl)
+	_, err = conn.Do("")
+	PutConn(conn)
+	if err != nil {
+		return "", err
+	}
+
+	var firstErr error
+	for i, err := range EnqueueBatch(b.jobs) {
+		if err == nil {
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		conn, errCon := GetConn()
+		if errCon != nil {
+			return id, errCon
+		}
+		if errFinish := finishBatch(conn, b.jobs[i], err); errFinish != nil {
+			logger.Criticalf("Error counting unenqueued job %v in batch %s: %v", b.jobs[i].Payload.ID, id, errFinish)
+		}
+		PutConn(conn)
+	}
+	return id, firstErr
+}
+
+func newBatchCallback(id string, job *Job) ([]byte, error) {
+	if job.Payload.Meta == nil {
+		job.Payload.Meta = make(map[string]interface{})
+	}
+	job.Payload.Meta["batch_id"] = id
+
+	callback, err := newStoredJob(job)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(callback)
+}
+
+// BatchStatus returns the progress of the batch with the
+// given ID.
+func BatchStatus(id string) (*BatchState, error) {
+	if err := Init(); err != nil {
+		return nil, err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return nil, err
+	}
+	defer PutConn(conn)
+
+	values, err := redis.StringMap(conn.Do("HGETALL", batchKey(id)))
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, errorBatchNotFound
+	}
+
+	state := &BatchState{ID: id}
+	fmt.Sscan(values["total"], &state.Total)
+	fmt.Sscan(values["pending"], &state.Pending)
+	fmt.Sscan(values["succeeded"], &state.Succeeded)
+	fmt.Sscan(values["failed"], &state.Failed)
+	return state, nil
+}
+
+// finishBatch records in its batch that job finished with
+// err, firing the callbacks if it was the last job.
+func finishBatch(conn *RedisConn, job *Job, err error) error {
+	id, ok := job.Payload.Meta["batch_id"].(string)
+	if !ok || job.Payload.ID == "" {
+		return nil
+	}
+
+	outcome := "succeeded"
+	if err != nil {
+		outcome = "failed"
+	}
+
+	fired, err := redis.Int(conn.runScript(finishBatchScript, batchKey(id), batchJobsKey(id), job.Payload.ID, outcome, fmt.Sprintf("%squeues", workerSettings.Namespace)))
+	if err != nil {
+		return err
+	}
+	if fired > 0 {
+		logger.Infof("Batch %s complete, enqueued %d callbacks", id, fired)
+	}
+	return nil
+}
+
+func batchKey(id string) string {
+	return fmt.Sprintf("%sbatch:%s", workerSettings.Namespace, id)
+}
+
+func batchJobsKey(id string) string {
+	return fmt.Sprintf("%sbatch:%s:jobs", workerSettings.Namespace, id)
+}
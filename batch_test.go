@@ -0,0 +1,88 @@
+package goworker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestBatch(t *testing.T) {
+	workerSettings.Queues = []string{"batchJobs"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+
+	var batchTests = []struct {
+		failures  int
+		callbacks []string
+	}{
+		{0, []string{"Completed", "Succeeded"}},
+		{1, []string{"Completed"}},
+	}
+
+	w := &worker{}
+	for _, tt := range batchTests {
+		conn.Do("DEL", queueKey("batchJobs"), queueKey("batchCallbacks"))
+
+		batch := NewBatch()
+		batch.OnComplete = &Job{Queue: "batchCallbacks", Payload: Payload{Class: "Completed", Args: []interface{}{}}}
+		batch.OnSuccess = &Job{Queue: "batchCallbacks", Payload: Payload{Class: "Succeeded", Args: []interface{}{}}}
+		for i := 0; i < 3; i++ {
+			batch.Add(&Job{Queue: "batchJobs", Payload: Payload{Class: "Batched", Args: []interface{}{i}}})
+		}
+		id, err := batch.Enqueue()
+		if err != nil {
+			t.Fatalf("Batch: enqueue error %s", err)
+		}
+
+		for i := 0; i < 3; i++ {
+			job := popTestJob(t, conn, "batchJobs")
+			if job == nil {
+				t.Fatalf("Batch: expected job %d on the queue", i)
+			}
+			var jobErr error
+			if i < tt.failures {
+				jobErr = errors.New("batched job failed")
+			}
+			w.finish(conn, job, jobErr)
+			// Finishing a job twice must not count it twice.
+			w.finish(conn, job, nil)
+			conn.Do("")
+		}
+
+		state, err := BatchStatus(id)
+		if err != nil {
+			t.Fatalf("BatchStatus: error %s", err)
+		}
+		if state.Total != 3 || state.Pending != 0 || state.Failed != tt.failures || state.Succeeded != 3-tt.failures {
+			t.Errorf("BatchStatus: expected 3 finished with %d failures, actual %+v", tt.failures, state)
+		}
+
+		var classes []string
+		for job := popTestJob(t, conn, "batchCallbacks"); job != nil; job = popTestJob(t, conn, "batchCallbacks") {
+			classes = append(classes, job.Payload.Class)
+			if job.Payload.Meta["batch_id"] != id {
+				t.Errorf("Batch: expected callback with batch_id %s, actual %v", id, job.Payload.Meta)
+			}
+		}
+		if len(classes) != len(tt.callbacks) || (len(classes) > 0 && classes[0] != tt.callbacks[0]) {
+			t.Errorf("Batch: expected callbacks %v, actual %v", tt.callbacks, classes)
+		}
+
+		if member, _ := redis.Bool(conn.Do("SISMEMBER", workerSettings.Namespace+"queues", "batchCallbacks")); !member {
+			t.Errorf("Batch: expected batchCallbacks in the queues set")
+		}
+	}
+
+	if _, err := NewBatch().Enqueue(); err != errorEmptyBatch {
+		t.Errorf("Batch: expected err %v, actual err %v", errorEmptyBatch, err)
+	}
+}
@@ -0,0 +1,388 @@
+package goworker
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// clusterSlotCount is the number of hash slots keys are
+// spread over in a Redis Cluster.
+const clusterSlotCount = 16384
+
+// clusterMaxRedirects bounds how many MOVED and ASK
+// redirects are followed for one command.
+const clusterMaxRedirects = 5
+
+var (
+	errorNoClusterNodes     = errors.New("no nodes in Redis Cluster URI")
+	errorTooManyRedirects   = errors.New("too many Redis Cluster redirects")
+	errorClusterConnClosed  = errors.New("Redis Cluster connection closed")
+	errorInvalidClusterSlot = errors.New("invalid Redis Cluster slots reply")
+)
+
+// clusterConn is a redis.Conn that sends each command to the
+// node serving the slot of its key, following MOVED and ASK
+// redirects. Pipelined commands are sent one at a time when
+// flushed, since they may be served by different nodes.
+type clusterConn struct {
+	seeds       []string
+	dialOptions []redis.DialOption
+	nodes       map[string]redis.Conn
+	slots       [clusterSlotCount]string
+	pending     []clusterCommand
+	replies     []interface{}
+	closed      bool
+}
+
+type clusterCommand struct {
+	name string
+	args []interface{}
+}
+
+func newClusterConn(seeds []string, dialOptions []redis.DialOption) (*clusterConn, error) {
+	if len(seeds) == 0 || seeds[0] == "" {
+		return nil, errorNoClusterNodes
+	}
+
+	c := &clusterConn{
+		seeds:       seeds,
+		dialOptions: dialOptions,
+		nodes:       make(map[string]redis.Conn),
+	}
+	if err := c.refresh(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// refresh loads the slot table from the first seed or known
+// node that answers CLUSTER SLOTS.
+func (c *clusterConn) refresh() error {
+	addrs := append([]string{}, c.seeds...)
+	for addr := range c.nodes {
+		addrs = append(addrs, addr)
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := c.node(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		reply, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+		if err != nil {
+			c.drop(addr)
+			lastErr = err
+			continue
+		}
+		if err := c.setSlots(addr, reply); err != nil {
+			return err
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// setSlots fills the slot table from a CLUSTER SLOTS reply
+// given by the node at addr.
+func (c *clusterConn) setSlots(addr string, reply []interface{}) error {
+	for _, entry := range reply {
+		values, err := redis.Values(entry, nil)
+		if err != nil || len(values) < 3 {
+			return errorInvalidClusterSlot
+		}
+		start, err := redis.Int(values[0], nil)
+		if err != nil {
+			return errorInvalidClusterSlot
+		}
+		end, err := redis.Int(values[1], nil)
+		if err != nil || start < 0 || end >= clusterSlotCount {
+			return errorInvalidClusterSlot
+		}
+		master, err := redis.Values(values[2], nil)
+		if err != nil || len(master) < 2 {
+			return errorInvalidClusterSlot
+		}
+		host, _ := redis.String(master[0], nil)
+		port, err := redis.Int(master[1], nil)
+		if err != nil {
+			return errorInvalidClusterSlot
+		}
+		if host == "" {
+			host, _, _ = net.SplitHostPort(addr)
+		}
+
+		nodeAddr := net.JoinHostPort(host, strconv.Itoa(port))
+		for slot := start; slot <= end; slot++ {
+			c.slots[slot] = nodeAddr
+		}
+	}
+	return nil
+}
+
+// node returns the connection to the node at addr, dialing
+// it if needed.
+func (c *clusterConn) node(addr string) (redis.Conn, error) {
+	if conn, ok := c.nodes[addr]; ok {
+		return conn, nil
+	}
+	conn, err := redis.Dial("tcp", addr, c.dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	c.nodes[addr] = conn
+	return conn, nil
+}
+
+// drop closes and forgets the connection to the node at
+// addr.
+func (c *clusterConn) drop(addr string) {
+	if conn, ok := c.nodes[addr]; ok {
+		conn.Close()
+		delete(c.nodes, addr)
+	}
+}
+
+// addr returns the address of the node that should serve
+// the command.
+func (c *clusterConn) addr(commandName string, args []interface{}) string {
+	if key, ok := clusterKey(commandName, args); ok {
+		if addr := c.slots[clusterSlot(key)]; addr != "" {
+			return addr
+		}
+	}
+	for addr := range c.nodes {
+		return addr
+	}
+	return c.seeds[0]
+}
+
+// do sends one command to the node serving its key and
+// returns its reply, following redirects.
+func (c *clusterConn) do(commandName string, args []interface{}) (interface{}, error) {
+	addr := c.addr(commandName, args)
+	asking := false
+	for i := 0; i <= clusterMaxRedirects; i++ {
+		conn, err := c.node(addr)
+		if err != nil {
+			c.refresh()
+			return nil, err
+		}
+
+		if asking {
+			conn.Send("ASKING")
+		}
+		reply, err := conn.Do(commandName, args...)
+		if err != nil && conn.Err() != nil {
+			c.drop(addr)
+			c.refresh()
+			return nil, err
+		}
+
+		redirect, slot, target, ok := parseClusterRedirect(err)
+		if !ok {
+			return reply, err
+		}
+		addr = target
+		asking = redirect == "ASK"
+		if !asking {
+			c.slots[slot] = target
+		}
+	}
+	return nil, errorTooManyRedirects
+}
+
+// flush runs the pending commands and queues their replies.
+func (c *clusterConn) flush() error {
+	pending := c.pending
+	c.pending = nil
+	for _, command := range pending {
+		reply, err := c.do(command.name, command.args)
+		if err != nil {
+			if _, ok := err.(redis.Error); !ok {
+				return err
+			}
+			reply = err
+		}
+		c.replies = append(c.replies, reply)
+	}
+	return nil
+}
+
+func (c *clusterConn) Close() error {
+	c.closed = true
+	for addr := range c.nodes {
+		c.drop(addr)
+	}
+	return nil
+}
+
+func (c *clusterConn) Err() error {
+	if c.closed {
+		return errorClusterConnClosed
+	}
+	return nil
+}
+
+func (c *clusterConn) Send(commandName string, args ...interface{}) error {
+	if c.closed {
+		return errorClusterConnClosed
+	}
+	c.pending = append(c.pending, clusterCommand{commandName, args})
+	return nil
+}
+
+func (c *clusterConn) Flush() error {
+	if c.closed {
+		return errorClusterConnClosed
+	}
+	return c.flush()
+}
+
+func (c *clusterConn) Receive() (interface{}, error) {
+	if c.closed {
+		return nil, errorClusterConnClosed
+	}
+	if len(c.replies) == 0 {
+		if err := c.flush(); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.replies) == 0 {
+		return nil, errors.New("no pending Redis Cluster replies")
+	}
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	if err, ok := reply.(redis.Error); ok {
+		return reply, err
+	}
+	return reply, nil
+}
+
+// Do behaves like the Do of a single connection: it flushes
+// the pending commands and, with an empty command, returns
+// all of their replies, or otherwise returns the reply of
+// the command and the first error among all of them.
+func (c *clusterConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if c.closed {
+		return nil, errorClusterConnClosed
+	}
+	if err := c.flush(); err != nil {
+		return nil, err
+	}
+	replies := c.replies
+	c.replies = nil
+
+	if commandName == "" {
+		if len(replies) == 0 {
+			return nil, nil
+		}
+		return replies, nil
+	}
+
+	var err error
+	for _, reply := range replies {
+		if e, ok := reply.(redis.Error); ok && err == nil {
+			err = e
+		}
+	}
+	reply, e := c.do(commandName, args)
+	if err == nil {
+		err = e
+	}
+	return reply, err
+}
+
+// parseClusterRedirect reads a MOVED or ASK error, such as
+// MOVED 3999 127.0.0.1:6381.
+func parseClusterRedirect(err error) (redirect string, slot int, addr string, ok bool) {
+	redisErr, isRedisErr := err.(redis.Error)
+	if !isRedisErr {
+		return
+	}
+	fields := strings.Fields(string(redisErr))
+	if len(fields) != 3 || (fields[0] != "MOVED" && fields[0] != "ASK") {
+		return
+	}
+	slot, convErr := strconv.Atoi(fields[1])
+	if convErr != nil || slot < 0 || slot >= clusterSlotCount {
+		return
+	}
+	return fields[0], slot, fields[2], true
+}
+
+// clusterKey returns the key a command acts on, which
+// decides the node it is sent to.
+func clusterKey(commandName string, args []interface{}) (string, bool) {
+	switch strings.ToUpper(commandName) {
+	case "", "PING", "ECHO", "INFO", "TIME", "AUTH", "CLUSTER", "SCRIPT", "ASKING":
+		return "", false
+	case "EVAL", "EVALSHA":
+		if len(args) < 3 || clusterArg(args[1]) == "0" {
+			return "", false
+		}
+		return clusterArg(args[2]), true
+	}
+	if len(args) == 0 {
+		return "", false
+	}
+	return clusterArg(args[0]), true
+}
+
+func clusterArg(arg interface{}) string {
+	switch arg := arg.(type) {
+	case string:
+		return arg
+	case []byte:
+		return string(arg)
+	default:
+		return fmt.Sprint(arg)
+	}
+}
+
+// clusterSlot returns the hash slot of key. Only the part
+// between the first { and the next } is hashed when it is
+// not empty, so keys sharing such a hash tag share a slot.
+func clusterSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlotCount)
+}
+
+// crc16 is the CRC-16/XMODEM checksum used by Redis Cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// hashTagNamespace wraps the name of namespace in a hash tag,
+// so that resque: becomes {resque}:, placing every goworker
+// key in one Redis Cluster slot. A namespace that already
+// has a hash tag is returned unchanged.
+func hashTagNamespace(namespace string) string {
+	if namespace == "" || strings.Contains(namespace, "{") {
+		return namespace
+	}
+	name := strings.TrimSuffix(namespace, ":")
+	return "{" + name + "}" + namespace[len(name):]
+}
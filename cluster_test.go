@@ -0,0 +1,155 @@
+package goworker
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestClusterSlot(t *testing.T) {
+	var slotTests = []struct {
+		key  string
+		slot int
+	}{
+		{"123456789", 12739},
+		{"foo", 12182},
+		{"{foo}:queue:high", 12182},
+		{"resque:{foo}", 12182},
+		{"{}foo", clusterSlot("{}foo")},
+	}
+
+	for _, tt := range slotTests {
+		if slot := clusterSlot(tt.key); slot != tt.slot {
+			t.Errorf("clusterSlot(%q): expected %d, actual %d", tt.key, tt.slot, slot)
+		}
+	}
+	if clusterSlot("{}foo") == clusterSlot("") {
+		t.Errorf("clusterSlot: expected an empty hash tag to hash the whole key")
+	}
+}
+
+func TestHashTagNamespace(t *testing.T) {
+	var namespaceTests = []struct {
+		namespace string
+		expected  string
+	}{
+		{"resque:", "{resque}:"},
+		{"resque", "{resque}"},
+		{"{resque}:", "{resque}:"},
+		{"", ""},
+	}
+
+	for _, tt := range namespaceTests {
+		if actual := hashTagNamespace(tt.namespace); actual != tt.expected {
+			t.Errorf("hashTagNamespace(%q): expected %q, actual %q", tt.namespace, tt.expected, actual)
+		}
+	}
+}
+
+func TestClusterKey(t *testing.T) {
+	var keyTests = []struct {
+		command string
+		args    []interface{}
+		key     string
+		ok      bool
+	}{
+		{"LPOP", []interface{}{"resque:queue:high"}, "resque:queue:high", true},
+		{"rpush", []interface{}{[]byte("resque:queue:low"), "job"}, "resque:queue:low", true},
+		{"EVALSHA", []interface{}{"sha", 2, "resque:workflow:1", "other"}, "resque:workflow:1", true},
+		{"EVAL", []interface{}{"return 1", 0}, "", false},
+		{"PING", nil, "", false},
+		{"", nil, "", false},
+	}
+
+	for _, tt := range keyTests {
+		key, ok := clusterKey(tt.command, tt.args)
+		if key != tt.key || ok != tt.ok {
+			t.Errorf("clusterKey(%s, %v): expected %q %v, actual %q %v", tt.command, tt.args, tt.key, tt.ok, key, ok)
+		}
+	}
+}
+
+// fakeClusterNode records the commands it is sent.
+type fakeClusterNode struct {
+	sync.Mutex
+	addr     string
+	commands []string
+}
+
+func (n *fakeClusterNode) record(args []string) {
+	n.Lock()
+	defer n.Unlock()
+	n.commands = append(n.commands, strings.Join(args, " "))
+}
+
+func (n *fakeClusterNode) received() []string {
+	n.Lock()
+	defer n.Unlock()
+	return append([]string{}, n.commands...)
+}
+
+func TestClusterConnRedirects(t *testing.T) {
+	owner := &fakeClusterNode{}
+
+	owner.addr = serveFakeRedis(t, func(args []string) string {
+		owner.record(args)
+		switch strings.ToUpper(args[0]) {
+		case "ASKING":
+			return "+OK\r\n"
+		case "SET":
+			return "+OK\r\n"
+		}
+		return "$3\r\nbar\r\n"
+	})
+	seed := &fakeClusterNode{}
+	seed.addr = serveFakeRedis(t, func(args []string) string {
+		seed.record(args)
+		_, port, _ := net.SplitHostPort(seed.addr)
+		switch strings.ToUpper(args[0]) {
+		case "CLUSTER":
+			return fmt.Sprintf("*1\r\n*3\r\n:0\r\n:16383\r\n*2\r\n$0\r\n\r\n:%s\r\n", port)
+		case "SET":
+			return fmt.Sprintf("-ASK %d %s\r\n", clusterSlot(args[1]), owner.addr)
+		}
+		return fmt.Sprintf("-MOVED %d %s\r\n", clusterSlot(args[1]), owner.addr)
+	})
+
+	conn, err := redisConnFromURI(fmt.Sprintf("redis-cluster://%s/", seed.addr))
+	if err != nil {
+		t.Fatalf("redisConnFromURI: error %s", err)
+	}
+	defer conn.Close()
+
+	for i := 0; i < 2; i++ {
+		if reply, err := redis.String(conn.Do("GET", "foo")); reply != "bar" || err != nil {
+			t.Errorf("Do(GET): expected bar, actual %q %v", reply, err)
+		}
+	}
+	if commands := seed.received(); len(commands) != 2 {
+		t.Errorf("Do(GET): expected the seed to be asked once after MOVED, actual %v", commands)
+	}
+
+	// The slot of bar is still served by the seed, which
+	// asks for it to be tried on the owner this once.
+	if reply, err := redis.String(conn.Do("SET", "bar", "baz")); reply != "OK" || err != nil {
+		t.Errorf("Do(SET): expected OK, actual %q %v", reply, err)
+	}
+	commands := owner.received()
+	if len(commands) < 2 || commands[len(commands)-2] != "ASKING" || commands[len(commands)-1] != "SET bar baz" {
+		t.Errorf("Do(SET): expected ASKING before SET on the owner, actual %v", commands)
+	}
+
+	conn.Send("GET", "foo")
+	conn.Send("SET", "bar", "baz")
+	replies, err := redis.Values(conn.Do(""))
+	if err != nil || len(replies) != 2 {
+		t.Fatalf("Do(\"\"): expected 2 replies, actual %v %v", replies, err)
+	}
+	if reply, _ := redis.String(replies[0], nil); reply != "bar" {
+		t.Errorf("Do(\"\"): expected bar, actual %v", replies[0])
+	}
+}
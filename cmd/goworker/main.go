@@ -0,0 +1,271 @@
+// Command goworker inspects and manages the Redis data
+// shared by goworker and Resque.
+//
+// Usage:
+//
+//	goworker [flags] <command> [arguments]
+//
+// The commands are:
+//
+//	queues                            list queues and their sizes
+//	workers                           list workers and their current jobs
+//	failed list [start] [count]       list failed jobs
+//	failed retry <index>...           requeue failed jobs
+//	failed remove <index>...          delete failed jobs
+//	failed clear                      delete every failed job
+//	enqueue <queue> <class> <json-args>
+//	                                  push a job onto a queue
+//	stats                             show processed, failed and pending totals
+//
+// The flags are the same as those of a goworker process,
+// so -uri, -namespace, -tls-cert and -insecure-tls select
+// the Redis database to operate on.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/benmanns/goworker"
+)
+
+var (
+	errorUsage = errors.New("invalid arguments")
+)
+
+const usageText = `usage: goworker [flags] <command> [arguments]
+
+commands:
+  queues                               list queues and their sizes
+  workers                              list workers and their current jobs
+  failed list [start] [count]          list failed jobs
+  failed retry <index>...              requeue failed jobs
+  failed remove <index>...             delete failed jobs
+  failed clear                         delete every failed job
+  enqueue <queue> <class> <json-args>  push a job onto a queue
+  stats                                show processed, failed and pending totals
+
+flags:
+`
+
+func usage() {
+	fmt.Fprint(os.Stderr, usageText)
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Set("use-number", "true")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := goworker.Init(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	defer goworker.Close()
+
+	var err error
+	args := flag.Args()[1:]
+	switch flag.Arg(0) {
+	case "queues":
+		err = queues(args)
+	case "workers":
+		err = workers(args)
+	case "failed":
+		err = failed(args)
+	case "enqueue":
+		err = enqueue(args)
+	case "stats":
+		err = stats(args)
+	default:
+		err = errorUsage
+	}
+
+	if err == errorUsage {
+		usage()
+		goworker.Close()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		goworker.Close()
+		os.Exit(1)
+	}
+}
+
+func queues(args []string) error {
+	if len(args) != 0 {
+		return errorUsage
+	}
+
+	queues, err := goworker.QueueSizes()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "QUEUE\tSIZE")
+	for _, queue := range queues {
+		fmt.Fprintf(w, "%s\t%d\n", queue.Name, queue.Size)
+	}
+	return w.Flush()
+}
+
+func workers(args []string) error {
+	if len(args) != 0 {
+		return errorUsage
+	}
+
+	workers, err := goworker.Workers()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKER\tQUEUE\tCLASS\tRUN AT\tARGS")
+	for _, worker := range workers {
+		if worker.Job == nil {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\n", worker.Name)
+			continue
+		}
+		args, err := json.Marshal(worker.Job.Payload.Args)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", worker.Name, worker.Job.Queue, worker.Job.Payload.Class, worker.RunAt.Format("2006-01-02 15:04:05"), args)
+	}
+	return w.Flush()
+}
+
+func failed(args []string) error {
+	if len(args) == 0 {
+		return errorUsage
+	}
+
+	switch args[0] {
+	case "list":
+		return failedList(args[1:])
+	case "retry":
+		return eachIndex(args[1:], goworker.RetryFailure)
+	case "remove":
+		return eachIndex(args[1:], goworker.RemoveFailure)
+	case "clear":
+		if len(args) != 1 {
+			return errorUsage
+		}
+		return goworker.ClearFailures()
+	}
+	return errorUsage
+}
+
+func failedList(args []string) error {
+	if len(args) > 2 {
+		return errorUsage
+	}
+
+	numbers := []int{0, 0}
+	for i, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return errorUsage
+		}
+		numbers[i] = n
+	}
+
+	failures, err := goworker.Failures(numbers[0], numbers[1])
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tFAILED AT\tQUEUE\tEXCEPTION\tERROR\tPAYLOAD")
+	for i, failure := range failures {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", numbers[0]+i, failure.FailedAt, failure.Queue, failure.Exception, oneLine(failure.Error), failure.Payload)
+	}
+	return w.Flush()
+}
+
+// eachIndex calls fn for every index in args. Indexes are
+// processed from highest to lowest so that removing one
+// does not shift the others.
+func eachIndex(args []string, fn func(int) error) error {
+	if len(args) == 0 {
+		return errorUsage
+	}
+
+	indexes := make([]int, len(args))
+	for i, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return errorUsage
+		}
+		indexes[i] = n
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(indexes)))
+
+	for _, index := range indexes {
+		if err := fn(index); err != nil {
+			return fmt.Errorf("index %d: %v", index, err)
+		}
+	}
+	return nil
+}
+
+func enqueue(args []string) error {
+	if len(args) != 3 {
+		return errorUsage
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(args[2])))
+	decoder.UseNumber()
+
+	var jobArgs []interface{}
+	if err := decoder.Decode(&jobArgs); err != nil {
+		return fmt.Errorf("arguments must be a JSON array: %v", err)
+	}
+
+	return goworker.Enqueue(&goworker.Job{
+		Queue: args[0],
+		Payload: goworker.Payload{
+			Class: args[1],
+			Args:  jobArgs,
+		},
+	})
+}
+
+func stats(args []string) error {
+	if len(args) != 0 {
+		return errorUsage
+	}
+
+	stats, err := goworker.GetStats()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "processed\t%d\n", stats.Processed)
+	fmt.Fprintf(w, "failed\t%d\n", stats.Failed)
+	fmt.Fprintf(w, "pending\t%d\n", stats.Pending)
+	fmt.Fprintf(w, "queues\t%d\n", stats.Queues)
+	fmt.Fprintf(w, "workers\t%d\n", stats.Workers)
+	fmt.Fprintf(w, "working\t%d\n", stats.Working)
+	return w.Flush()
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
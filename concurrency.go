@@ -0,0 +1,214 @@
+package goworker
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	errorInvalidConcurrency = errors.New("concurrency must be at least 1")
+)
+
+var (
+	activeScaler *scaler
+	scalerMutex  sync.Mutex
+
+	// concurrencyMutex guards workerSettings.Concurrency,
+	// which the scaler, signal handlers, the concurrency
+	// watcher and SetConcurrency all change.
+	concurrencyMutex sync.Mutex
+)
+
+// concurrency returns the concurrency setting.
+func concurrency() int {
+	concurrencyMutex.Lock()
+	defer concurrencyMutex.Unlock()
+	return workerSettings.Concurrency
+}
+
+// setConcurrency changes the concurrency setting to n,
+// returning the previous setting.
+func setConcurrency(n int) int {
+	concurrencyMutex.Lock()
+	defer concurrencyMutex.Unlock()
+	previous := workerSettings.Concurrency
+	workerSettings.Concurrency = n
+	return previous
+}
+
+// scaler owns the workers started by Work and grows or
+// shrinks their number while the process is running.
+type scaler struct {
+	sync.Mutex
+	cond    *sync.Cond
+	jobs    <-chan *Job
+	queues  []string
+	nextID  int
+	running int
+	closed  bool
+	stops   []chan struct{}
+}
+
+func newScaler(jobs <-chan *Job, queues []string) *scaler {
+	s := &scaler{
+		jobs:   jobs,
+		queues: queues,
+	}
+	s.cond = sync.NewCond(&s.Mutex)
+	return s
+}
+
+// resize starts or stops workers until n are running.
+// Stopped workers finish their current job before they
+// unregister.
+func (s *scaler) resize(n int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.resizeLocked(n)
+}
+
+// resizeLocked is resize for a caller holding the lock.
+func (s *scaler) resizeLocked(n int) error {
+	if n < 1 {
+		return errorInvalidConcurrency
+	}
+	if s.closed {
+		return nil
+	}
+
+	for len(s.stops) < n {
+		worker, err := newWorker(strconv.Itoa(s.nextID), s.queues)
+		if err != nil {
+			return err
+		}
+		stop := make(chan struct{})
+		var monitor sync.WaitGroup
+		if err := worker.work(s.jobs, stop, &monitor); err != nil {
+			return err
+		}
+		s.nextID++
+		s.stops = append(s.stops, stop)
+		s.running++
+
+		go func() {
+			monitor.Wait()
+			s.exited(stop)
+		}()
+	}
+
+	for len(s.stops) > n {
+		last := len(s.stops) - 1
+		close(s.stops[last])
+		s.stops = s.stops[:last]
+	}
+
+	if previous := setConcurrency(n); previous != n {
+		logger.Infof("Concurrency changed from %d to %d", previous, n)
+	}
+
+	return nil
+}
+
+// adjust adds delta to the number of running workers.
+func (s *scaler) adjust(delta int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.resizeLocked(len(s.stops) + delta)
+}
+
+// exited records that the worker owning stop has returned.
+// A worker that was not stopped by resize has seen the jobs
+// channel close, so no more workers may be started.
+func (s *scaler) exited(stop chan struct{}) {
+	s.Lock()
+	defer s.Unlock()
+
+	for i, c := range s.stops {
+		if c == stop {
+			s.stops = append(s.stops[:i], s.stops[i+1:]...)
+			s.closed = true
+			break
+		}
+	}
+	s.running--
+	s.cond.Broadcast()
+}
+
+// wait blocks until every worker has returned.
+func (s *scaler) wait() {
+	s.Lock()
+	defer s.Unlock()
+
+	for s.running > 0 {
+		s.cond.Wait()
+	}
+}
+
+// watch polls key for the desired concurrency every
+// interval until done is closed.
+func (s *scaler) watch(key string, interval time.Duration, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(interval):
+		}
+
+		conn, err := GetConn()
+		if err != nil {
+			logger.Criticalf("Error on getting connection in concurrency watcher: %v", err)
+			continue
+		}
+		n, err := redis.Int(conn.Do("GET", fmt.Sprintf("%s%s", workerSettings.Namespace, key)))
+		PutConn(conn)
+		if err == redis.ErrNil {
+			continue
+		}
+		if err != nil {
+			logger.Errorf("Error reading concurrency from %s: %v", key, err)
+			continue
+		}
+
+		s.Lock()
+		if n != len(s.stops) {
+			if err := s.resizeLocked(n); err != nil {
+				logger.Errorf("Error setting concurrency to %d from %s: %v", n, key, err)
+			}
+		}
+		s.Unlock()
+	}
+}
+
+func setActiveScaler(s *scaler) {
+	scalerMutex.Lock()
+	defer scalerMutex.Unlock()
+
+	activeScaler = s
+}
+
+// SetConcurrency changes the number of concurrently
+// executing workers. If Work is running, workers are
+// started or stopped to match; stopped workers finish their
+// current job before they shut down. Otherwise, the value
+// is used the next time Work is called.
+func SetConcurrency(n int) error {
+	if n < 1 {
+		return errorInvalidConcurrency
+	}
+
+	scalerMutex.Lock()
+	defer scalerMutex.Unlock()
+
+	if activeScaler == nil {
+		setConcurrency(n)
+		return nil
+	}
+	return activeScaler.resize(n)
+}
@@ -0,0 +1,82 @@
+package goworker
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSetConcurrencyInvalid(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		if err := SetConcurrency(n); err != errorInvalidConcurrency {
+			t.Errorf("SetConcurrency(%d): expected err %v, actual err %v", n, errorInvalidConcurrency, err)
+		}
+	}
+}
+
+func TestScalerResize(t *testing.T) {
+	workerSettings.Queues = []string{"scalerQueue"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	jobs := make(chan *Job)
+	s := newScaler(jobs, workerSettings.Queues)
+
+	for _, n := range []int{3, 5, 1} {
+		if err := s.resize(n); err != nil {
+			t.Fatalf("Scaler: resize to %d error %s", n, err)
+		}
+		s.Lock()
+		actual := len(s.stops)
+		s.Unlock()
+		if actual != n {
+			t.Errorf("Scaler: resize to %d, actual %d workers", n, actual)
+		}
+	}
+
+	close(jobs)
+	s.wait()
+
+	if s.running != 0 {
+		t.Errorf("Scaler: expected 0 running workers after close, actual %d", s.running)
+	}
+	if err := s.resize(2); err != nil || len(s.stops) != 0 {
+		t.Errorf("Scaler: expected no workers after close, actual %d (err %v)", len(s.stops), err)
+	}
+}
+
+func TestScalerAdjustConcurrently(t *testing.T) {
+	workerSettings.Queues = []string{"scalerQueue"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	jobs := make(chan *Job)
+	s := newScaler(jobs, workerSettings.Queues)
+	if err := s.resize(10); err != nil {
+		t.Fatalf("Scaler: resize to 10 error %s", err)
+	}
+	setActiveScaler(s)
+	defer setActiveScaler(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(3)
+		go func() { defer wg.Done(); s.adjust(1) }()
+		go func() { defer wg.Done(); s.adjust(-1) }()
+		go func() { defer wg.Done(); SetConcurrency(10) }()
+	}
+	wg.Wait()
+
+	s.Lock()
+	actual := len(s.stops)
+	s.Unlock()
+	if actual != concurrency() {
+		t.Errorf("Scaler: expected %d workers to match the concurrency setting, actual %d", concurrency(), actual)
+	}
+
+	close(jobs)
+	s.wait()
+}
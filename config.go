@@ -0,0 +1,136 @@
+package goworker
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix starts the names of the environment variables
+// that set goworker flags, such as GOWORKER_QUEUES for
+// -queues.
+const envPrefix = "GOWORKER_"
+
+var errorConfigFormat = errors.New("the config file must end in .yaml, .yml, .toml or .json")
+
+// loadSettings sets the flags of fs from the config file and
+// then from GOWORKER_ environment variables, so that the
+// environment overrides the file. Flags given on the command
+// line override both.
+func loadSettings(fs *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	path := workerSettings.ConfigFile
+	if !explicit["config"] {
+		if env, ok := os.LookupEnv(envName("config")); ok {
+			path = env
+		}
+	}
+	if path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return err
+		}
+		for name, value := range values {
+			f := fs.Lookup(name)
+			if f == nil || name == "config" {
+				return fmt.Errorf("config %s: unknown setting %q", path, name)
+			}
+			if explicit[name] {
+				continue
+			}
+			// Setting the value directly leaves the flag
+			// unmarked, so later loads can still change it.
+			if err := f.Value.Set(value); err != nil {
+				return fmt.Errorf("config %s: %s: %v", path, name, err)
+			}
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || explicit[f.Name] || f.Name == "config" || err != nil {
+			return
+		}
+		if errSet := f.Value.Set(value); errSet != nil {
+			err = fmt.Errorf("$%s: %v", envName(f.Name), errSet)
+		}
+	})
+	return err
+}
+
+// envName returns the environment variable for the flag
+// called name.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// readConfigFile reads the settings in a YAML, TOML or JSON
+// file, chosen by its extension, as flag values keyed by
+// flag name. Keys may use _ in place of -, and lists are
+// joined with commas.
+func readConfigFile(path string) (map[string]string, error) {
+	buffer, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(buffer, &raw)
+	case ".toml":
+		err = toml.Unmarshal(buffer, &raw)
+	case ".json":
+		err = json.Unmarshal(buffer, &raw)
+	default:
+		err = errorConfigFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %v", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		name := strings.Replace(strings.ToLower(key), "_", "-", -1)
+		values[name], err = configValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: %s: %v", path, key, err)
+		}
+	}
+	return values, nil
+}
+
+// configValue formats a decoded config value as a flag
+// value.
+func configValue(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case []interface{}:
+		items := make([]string, len(value))
+		for i, item := range value {
+			var err error
+			if items[i], err = configValue(item); err != nil {
+				return "", err
+			}
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		return "", errors.New("nested settings are not supported")
+	case nil:
+		return "", nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
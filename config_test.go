@@ -0,0 +1,136 @@
+package goworker
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// withTestFlags registers the goworker flags on a new flag
+// set parsed from args, restoring the settings afterwards.
+func withTestFlags(t *testing.T, args ...string) *flag.FlagSet {
+	settings, flags := workerSettings, settingsFlags
+	t.Cleanup(func() {
+		workerSettings, settingsFlags = settings, flags
+	})
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse: error %s", err)
+	}
+	return fs
+}
+
+func TestLoadSettingsPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goworker.yaml")
+	config := "queues: [high, low]\nconcurrency: 10\nnamespace: \"file:\"\npool_timeout: 5s\nuse-number: true\n"
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatalf("WriteFile: error %s", err)
+	}
+	t.Setenv("GOWORKER_CONFIG", path)
+	t.Setenv("GOWORKER_NAMESPACE", "env:")
+	t.Setenv("GOWORKER_CONCURRENCY", "20")
+
+	fs := withTestFlags(t, "-concurrency=30")
+	if err := loadSettings(fs); err != nil {
+		t.Fatalf("loadSettings: error %s", err)
+	}
+
+	if workerSettings.QueuesString != "high,low" {
+		t.Errorf("loadSettings: expected queues from the file, actual %q", workerSettings.QueuesString)
+	}
+	if workerSettings.PoolTimeout != 5*time.Second || !workerSettings.UseNumber {
+		t.Errorf("loadSettings: expected pool timeout and use-number from the file, actual %v %v", workerSettings.PoolTimeout, workerSettings.UseNumber)
+	}
+	if workerSettings.Namespace != "env:" {
+		t.Errorf("loadSettings: expected the environment to override the file, actual %q", workerSettings.Namespace)
+	}
+	if workerSettings.Concurrency != 30 {
+		t.Errorf("loadSettings: expected the flag to override the environment, actual %d", workerSettings.Concurrency)
+	}
+	if workerSettings.IntervalFloat != 5.0 {
+		t.Errorf("loadSettings: expected the default interval, actual %v", workerSettings.IntervalFloat)
+	}
+}
+
+func TestSetSettingsSkipsEnvironmentAndFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goworker.yaml")
+	if err := os.WriteFile(path, []byte("queues: [file]\n"), 0600); err != nil {
+		t.Fatalf("WriteFile: error %s", err)
+	}
+	t.Setenv("GOWORKER_CONFIG", path)
+	t.Setenv("GOWORKER_NAMESPACE", "env:")
+	t.Setenv("GOWORKER_CONCURRENCY", "20")
+
+	settingsFlags = withTestFlags(t)
+	t.Cleanup(func() { settingsFromCode = false })
+	SetSettings(WorkerSettings{QueuesString: "code", Namespace: "code:", Concurrency: 10, UseNumber: true})
+	if err := flags(); err != nil {
+		t.Fatalf("flags: error %s", err)
+	}
+
+	if workerSettings.QueuesString != "code" || workerSettings.Namespace != "code:" {
+		t.Errorf("flags: expected the settings from code, actual queues %q namespace %q", workerSettings.QueuesString, workerSettings.Namespace)
+	}
+	if workerSettings.Concurrency != 10 {
+		t.Errorf("flags: expected the settings from code, actual concurrency %d", workerSettings.Concurrency)
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	var configTests = []struct {
+		name   string
+		config string
+		err    bool
+	}{
+		{"goworker.yml", "queues:\n  - high\n  - low\ninterval: 2.5\nexit-on-complete: true\n", false},
+		{"goworker.toml", "queues = [\"high\", \"low\"]\ninterval = 2.5\nexit_on_complete = true\n", false},
+		{"goworker.json", `{"queues": ["high", "low"], "interval": 2.5, "exit_on_complete": true}`, false},
+		{"goworker.json", `{"queues": {"high": 2}}`, true},
+		{"goworker.ini", "queues=high", true},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range configTests {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, []byte(tt.config), 0600); err != nil {
+			t.Fatalf("WriteFile: error %s", err)
+		}
+
+		values, err := readConfigFile(path)
+		if tt.err {
+			if err == nil {
+				t.Errorf("readConfigFile(%s): expected an error for %s", tt.name, tt.config)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("readConfigFile(%s): error %s", tt.name, err)
+			continue
+		}
+		if values["queues"] != "high,low" || values["interval"] != "2.5" || values["exit-on-complete"] != "true" {
+			t.Errorf("readConfigFile(%s): unexpected values %v", tt.name, values)
+		}
+	}
+}
+
+func TestLoadSettingsErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goworker.json")
+	if err := os.WriteFile(path, []byte(`{"queue": "high"}`), 0600); err != nil {
+		t.Fatalf("WriteFile: error %s", err)
+	}
+
+	fs := withTestFlags(t, "-config="+path)
+	if err := loadSettings(fs); err == nil {
+		t.Errorf("loadSettings: expected an error for an unknown setting")
+	}
+
+	fs = withTestFlags(t)
+	t.Setenv("GOWORKER_CONCURRENCY", "many")
+	if err := loadSettings(fs); err == nil {
+		t.Errorf("loadSettings: expected an error for a malformed environment variable")
+	}
+}
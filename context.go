@@ -0,0 +1,21 @@
+package goworker
+
+import (
+	"golang.org/x/net/context"
+)
+
+type jobContextKey struct{}
+
+// newJobContext returns the context a worker function is
+// called with while it works job.
+func newJobContext(job *Job) context.Context {
+	return context.WithValue(context.Background(), jobContextKey{}, job)
+}
+
+// JobFromContext returns the job being worked by the
+// worker function that was called with ctx, giving access
+// to its ID, enqueued time, attempt and metadata.
+func JobFromContext(ctx context.Context) (*Job, bool) {
+	job, ok := ctx.Value(jobContextKey{}).(*Job)
+	return job, ok
+}
@@ -0,0 +1,295 @@
+// Package dashboard serves a web view of goworker and Resque
+// queues, workers, stats and failures, similar to
+// resque-web, as an http.Handler to mount in your own
+// server:
+//
+//	http.Handle("/resque/", dashboard.New("/resque"))
+//
+// It reads the same Redis keys as goworker processes, so it
+// uses the goworker settings and connection pool; call
+// goworker.Init before serving requests.
+//
+// The dashboard has no authentication of its own, so mount
+// it behind yours. Its buttons change the failed list with
+// POST requests, which are refused when their Origin or
+// Referer header names another host, so that other sites
+// cannot submit them from an operator's browser.
+package dashboard
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/benmanns/goworker"
+)
+
+// failuresPerPage is the number of failures shown on one
+// page of the failed list.
+const failuresPerPage = 20
+
+type dashboard struct {
+	prefix string
+	mux    *http.ServeMux
+}
+
+// New returns a handler serving the dashboard under prefix,
+// which must match the path it is mounted on, without a
+// trailing slash.
+func New(prefix string) http.Handler {
+	d := &dashboard{
+		prefix: strings.TrimSuffix(prefix, "/"),
+		mux:    http.NewServeMux(),
+	}
+	d.mux.HandleFunc("/", d.overview)
+	d.mux.HandleFunc("/workers", d.workers)
+	d.mux.HandleFunc("/failed", d.failed)
+	d.mux.HandleFunc("/failed/retry", d.failedAction(goworker.RetryFailure))
+	d.mux.HandleFunc("/failed/remove", d.failedAction(goworker.RemoveFailure))
+	d.mux.HandleFunc("/failed/clear", d.failedClear)
+	return http.StripPrefix(d.prefix, d.mux)
+}
+
+func (d *dashboard) overview(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" && r.URL.Path != "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	stats, err := goworker.GetStats()
+	if err != nil {
+		d.error(w, err)
+		return
+	}
+	queues, err := goworker.QueueSizes()
+	if err != nil {
+		d.error(w, err)
+		return
+	}
+	failed, err := goworker.FailureCount()
+	if err != nil {
+		d.error(w, err)
+		return
+	}
+
+	d.render(w, "overview", map[string]interface{}{
+		"Stats":  stats,
+		"Queues": queues,
+		"Failed": failed,
+	})
+}
+
+func (d *dashboard) workers(w http.ResponseWriter, r *http.Request) {
+	workers, err := goworker.Workers()
+	if err != nil {
+		d.error(w, err)
+		return
+	}
+
+	d.render(w, "workers", map[string]interface{}{
+		"Workers": workers,
+	})
+}
+
+func (d *dashboard) failed(w http.ResponseWriter, r *http.Request) {
+	start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+	if start < 0 {
+		start = 0
+	}
+
+	count, err := goworker.FailureCount()
+	if err != nil {
+		d.error(w, err)
+		return
+	}
+	failures, err := goworker.Failures(start, failuresPerPage)
+	if err != nil {
+		d.error(w, err)
+		return
+	}
+
+	type entry struct {
+		Index int
+		*goworker.FailedJob
+	}
+	entries := make([]entry, len(failures))
+	for i, failure := range failures {
+		entries[i] = entry{Index: start + i, FailedJob: failure}
+	}
+
+	data := map[string]interface{}{
+		"Count":    count,
+		"Start":    start,
+		"Failures": entries,
+	}
+	if start > 0 {
+		previous := start - failuresPerPage
+		if previous < 0 {
+			previous = 0
+		}
+		data["Previous"] = previous
+	}
+	if int64(start+failuresPerPage) < count {
+		data["Next"] = start + failuresPerPage
+	}
+	d.render(w, "failed", data)
+}
+
+// failedAction returns a handler that applies action to the
+// failure whose index is posted, then returns to the failed
+// list.
+func (d *dashboard) failedAction(action func(int) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !d.checkPost(w, r) {
+			return
+		}
+
+		index, err := strconv.Atoi(r.FormValue("index"))
+		if err != nil {
+			http.Error(w, "invalid index", http.StatusBadRequest)
+			return
+		}
+		if err := action(index); err != nil {
+			d.error(w, err)
+			return
+		}
+		http.Redirect(w, r, d.prefix+"/failed?start="+r.FormValue("start"), http.StatusSeeOther)
+	}
+}
+
+func (d *dashboard) failedClear(w http.ResponseWriter, r *http.Request) {
+	if !d.checkPost(w, r) {
+		return
+	}
+
+	if err := goworker.ClearFailures(); err != nil {
+		d.error(w, err)
+		return
+	}
+	http.Redirect(w, r, d.prefix+"/failed", http.StatusSeeOther)
+}
+
+// checkPost reports whether r is a POST from a page of the
+// dashboard's own host, replying with an error if it is not.
+// Browsers send an Origin or Referer header with a form POST,
+// so a request with neither comes from another kind of
+// client and is allowed.
+func (d *dashboard) checkPost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return true
+	}
+	if u, err := url.Parse(source); err != nil || u.Host != r.Host {
+		http.Error(w, "cross-origin request refused", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (d *dashboard) render(w http.ResponseWriter, name string, data map[string]interface{}) {
+	data["Prefix"] = d.prefix
+	data["Namespace"] = goworker.Namespace()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (d *dashboard) error(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+var templates = template.Must(template.New("dashboard").Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>goworker</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+nav a { margin-right: 1em; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+pre { margin: 0; white-space: pre-wrap; }
+form { display: inline; }
+</style>
+</head>
+<body>
+<nav>
+<a href="{{.Prefix}}/">Overview</a>
+<a href="{{.Prefix}}/workers">Workers</a>
+<a href="{{.Prefix}}/failed">Failed</a>
+<span>namespace {{.Namespace}}</span>
+</nav>
+{{end}}
+
+{{define "footer"}}</body>
+</html>
+{{end}}
+
+{{define "overview"}}{{template "header" .}}
+<h1>Overview</h1>
+<table>
+<tr><th>Processed</th><td>{{.Stats.Processed}}</td></tr>
+<tr><th>Failed</th><td>{{.Stats.Failed}}</td></tr>
+<tr><th>Pending</th><td>{{.Stats.Pending}}</td></tr>
+<tr><th>Workers</th><td>{{.Stats.Workers}}</td></tr>
+<tr><th>Working</th><td>{{.Stats.Working}}</td></tr>
+</table>
+<h2>Queues</h2>
+<table>
+<tr><th>Queue</th><th>Jobs</th></tr>
+{{range .Queues}}<tr><td>{{.Name}}</td><td>{{.Size}}</td></tr>
+{{end}}<tr><td><a href="{{.Prefix}}/failed">failed</a></td><td>{{.Failed}}</td></tr>
+</table>
+{{template "footer" .}}{{end}}
+
+{{define "workers"}}{{template "header" .}}
+<h1>Workers</h1>
+<table>
+<tr><th>Worker</th><th>Queues</th><th>Processed</th><th>Failed</th><th>Job</th></tr>
+{{range .Workers}}<tr>
+<td>{{.Name}}</td>
+<td>{{range $i, $q := .Queues}}{{if $i}}, {{end}}{{$q}}{{end}}</td>
+<td>{{.Processed}}</td>
+<td>{{.Failed}}</td>
+<td>{{if .Job}}{{.Job.Payload.Class}} on {{.Job.Queue}} since {{.RunAt.Format "2006-01-02 15:04:05"}}<pre>{{.Job.Payload.Args}}</pre>{{else}}Waiting for a job{{end}}</td>
+</tr>
+{{end}}</table>
+{{template "footer" .}}{{end}}
+
+{{define "failed"}}{{template "header" .}}
+<h1>Failed jobs</h1>
+<p>Showing {{len .Failures}} of {{.Count}}.
+{{if .Count}}<form method="post" action="{{.Prefix}}/failed/clear"><button>Clear all</button></form>{{end}}</p>
+<table>
+<tr><th>#</th><th>Failed at</th><th>Queue</th><th>Worker</th><th>Error</th><th>Payload</th><th></th></tr>
+{{range .Failures}}<tr>
+<td>{{.Index}}</td>
+<td>{{.FailedAt}}{{if .RetriedAt}}<br>retried {{.RetriedAt}}{{end}}</td>
+<td>{{.Queue}}</td>
+<td>{{.Worker}}</td>
+<td>{{.Exception}}: <pre>{{.Error}}</pre>{{range .Backtrace}}<pre>{{.}}</pre>{{end}}</td>
+<td><pre>{{printf "%s" .Payload}}</pre></td>
+<td>
+<form method="post" action="{{$.Prefix}}/failed/retry"><input type="hidden" name="index" value="{{.Index}}"><input type="hidden" name="start" value="{{$.Start}}"><button>Retry</button></form>
+<form method="post" action="{{$.Prefix}}/failed/remove"><input type="hidden" name="index" value="{{.Index}}"><input type="hidden" name="start" value="{{$.Start}}"><button>Remove</button></form>
+</td>
+</tr>
+{{end}}</table>
+<p>{{with .Previous}}<a href="{{$.Prefix}}/failed?start={{.}}">Previous</a>{{else}}{{if .Start}}<a href="{{$.Prefix}}/failed">Previous</a>{{end}}{{end}}
+{{with .Next}}<a href="{{$.Prefix}}/failed?start={{.}}">Next</a>{{end}}</p>
+{{template "footer" .}}{{end}}
+`))
@@ -0,0 +1,102 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/benmanns/goworker"
+)
+
+var dashboardPageTests = []struct {
+	path     string
+	status   int
+	contains string
+}{
+	{"/resque/", http.StatusOK, "<h1>Overview</h1>"},
+	{"/resque/workers", http.StatusOK, "<h1>Workers</h1>"},
+	{"/resque/failed", http.StatusOK, "<h1>Failed jobs</h1>"},
+	{"/resque/missing", http.StatusNotFound, ""},
+}
+
+func TestDashboardPages(t *testing.T) {
+	if err := goworker.Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer goworker.Close()
+
+	handler := New("/resque")
+	for _, tt := range dashboardPageTests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", tt.path, nil))
+		if recorder.Code != tt.status {
+			t.Errorf("Dashboard(%s): expected status %d, actual %d", tt.path, tt.status, recorder.Code)
+		}
+		if !strings.Contains(recorder.Body.String(), tt.contains) {
+			t.Errorf("Dashboard(%s): expected body to contain %q, actual %s", tt.path, tt.contains, recorder.Body)
+		}
+	}
+}
+
+func TestDashboardFailedActions(t *testing.T) {
+	if err := goworker.Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer goworker.Close()
+
+	handler := New("/resque")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/resque/failed/retry", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Dashboard: expected status %d for GET retry, actual %d", http.StatusMethodNotAllowed, recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest("POST", "/resque/failed/clear", nil)
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusSeeOther || recorder.Header().Get("Location") != "/resque/failed" {
+		t.Errorf("Dashboard: expected redirect to /resque/failed, actual %d %s", recorder.Code, recorder.Header().Get("Location"))
+	}
+
+	recorder = httptest.NewRecorder()
+	form := url.Values{"index": {"x"}}
+	request = httptest.NewRequest("POST", "/resque/failed/remove", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Dashboard: expected status %d for invalid index, actual %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestDashboardRefusesCrossOriginPosts(t *testing.T) {
+	if err := goworker.Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer goworker.Close()
+
+	var originTests = []struct {
+		header   string
+		value    string
+		expected int
+	}{
+		{"Origin", "http://evil.example", http.StatusForbidden},
+		{"Referer", "http://evil.example/page", http.StatusForbidden},
+		{"Origin", "null", http.StatusForbidden},
+		{"Origin", "http://example.com", http.StatusSeeOther},
+		{"Referer", "http://example.com/resque/failed", http.StatusSeeOther},
+	}
+
+	handler := New("/resque")
+	for _, tt := range originTests {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest("POST", "/resque/failed/clear", nil)
+		request.Header.Set(tt.header, tt.value)
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != tt.expected {
+			t.Errorf("Dashboard: expected status %d for %s %s, actual %d", tt.expected, tt.header, tt.value, recorder.Code)
+		}
+	}
+}
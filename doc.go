@@ -100,6 +100,24 @@
 //		return nil
 //	}
 //
+// To skip the type assertions, register a function that
+// takes a context and a single argument of any type with
+// RegisterTyped. The job arguments are decoded into it
+// with encoding/json, and jobs whose arguments do not
+// decode fail with a DecodeError without calling the
+// function.
+//
+//	type Order struct {
+//		ID     int64   `json:"id"`
+//		Amount float64 `json:"amount"`
+//	}
+//
+//	func init() {
+//		goworker.RegisterTyped("ChargeOrder", func(ctx context.Context, queue string, order Order) error {
+//			return charge(order.ID, order.Amount)
+//		})
+//	}
+//
 // For testing, it is helpful to use the redis-cli program
 // to insert jobs onto the Redis queue:
 //
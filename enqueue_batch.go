@@ -0,0 +1,141 @@
+package goworker
+
+import (
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// enqueueBatchSize is the largest number of jobs pushed by a
+// single command in EnqueueBatch.
+const enqueueBatchSize = 1000
+
+// EnqueueBatch pushes many jobs at once over a single
+// pipelined connection. Jobs are grouped by queue and
+// pushed with one RPUSH, or one script for priority queues,
+// per queue and chunk of up to 1000 jobs, so that jobs on
+// the same queue keep their relative order. It returns one
+// error per job, in the same order as jobs, which is nil
+// for every job that was enqueued.
+func EnqueueBatch(jobs []*Job) []error {
+	errs := make([]error, len(jobs))
+	fail := func(indexes []int, err error) {
+		for _, i := range indexes {
+			errs[i] = err
+		}
+	}
+
+	all := make([]int, len(jobs))
+	for i := range jobs {
+		all[i] = i
+	}
+
+	if err := Init(); err != nil {
+		fail(all, err)
+		return errs
+	}
+
+	var queues []string
+	byQueue := make(map[string][]int)
+	buffers := make([][]byte, len(jobs))
+	for i, job := range jobs {
+		if err := job.Payload.stamp(); err != nil {
+			errs[i] = err
+			continue
+		}
+		buffer, err := encodeJob(job)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		buffers[i] = buffer
+
+		if _, ok := byQueue[job.Queue]; !ok {
+			queues = append(queues, job.Queue)
+		}
+		byQueue[job.Queue] = append(byQueue[job.Queue], i)
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		logger.Criticalf("Error on getting connection on batch enqueue")
+		fail(all, err)
+		return errs
+	}
+	defer PutConn(conn)
+
+	// pending holds, for each command sent, the indexes of
+	// the jobs its reply applies to. The SADD registering a
+	// queue applies to none, as its jobs are pushed either
+	// way.
+	var pending [][]int
+	for _, queue := range queues {
+		indexes := byQueue[queue]
+		priority := isPriorityQueue(queue) && !isSidekiqQueue(queue)
+		command := "RPUSH"
+		if priority {
+			command = "EVAL"
+		} else if isSidekiqQueue(queue) {
+			command = "LPUSH"
+		}
+
+		for start := 0; start < len(indexes); start += enqueueBatchSize {
+			end := start + enqueueBatchSize
+			if end > len(indexes) {
+				end = len(indexes)
+			}
+			chunk := indexes[start:end]
+
+			args := make([]interface{}, 0, 2*len(chunk)+4)
+			if priority {
+				args = append(args, pushPrioritySource, 2)
+			}
+			args = append(args, queueKey(queue))
+			if priority {
+				args = append(args, prioritySeqKey(queue))
+			}
+			for _, i := range chunk {
+				if priority {
+					args = append(args, jobs[i].Payload.Priority)
+				}
+				args = append(args, buffers[i])
+			}
+			if err := conn.Send(command, args...); err != nil {
+				fail(chunk, err)
+				continue
+			}
+			pending = append(pending, chunk)
+		}
+
+		if err := conn.Send("SADD", queuesKey(queue), queue); err != nil {
+			logger.Criticalf("Cant register queue to list of use queues")
+			continue
+		}
+		pending = append(pending, nil)
+	}
+
+	reply, err := conn.Do("")
+	replies, _ := reply.([]interface{})
+	if err == nil && len(replies) < len(pending) {
+		err = fmt.Errorf("expected %d pipelined replies, got %d", len(pending), len(replies))
+	}
+	if err != nil {
+		for _, indexes := range pending {
+			fail(indexes, err)
+		}
+		return errs
+	}
+
+	// The replies to our commands are the last ones, after
+	// any left on the connection by an earlier user.
+	replies = replies[len(replies)-len(pending):]
+	for i, indexes := range pending {
+		if err, ok := replies[i].(redis.Error); ok {
+			if indexes == nil {
+				logger.Criticalf("Cant register queue to list of use queues: %v", err)
+			}
+			fail(indexes, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,98 @@
+package goworker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestEnqueueBatch(t *testing.T) {
+	workerSettings.Queues = []string{"batchA"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+	conn.Do("DEL", fmt.Sprintf("%squeue:batchA", workerSettings.Namespace), fmt.Sprintf("%squeue:batchB", workerSettings.Namespace))
+
+	var jobs []*Job
+	for i := 0; i < enqueueBatchSize+5; i++ {
+		queue := "batchA"
+		if i%2 == 1 {
+			queue = "batchB"
+		}
+		jobs = append(jobs, &Job{Queue: queue, Payload: Payload{Class: "Batched", Args: []interface{}{i}}})
+	}
+	jobs = append(jobs, &Job{Queue: "batchB", Payload: Payload{Class: "Unmarshalable", Args: []interface{}{make(chan int)}}})
+
+	errs := EnqueueBatch(jobs)
+	if len(errs) != len(jobs) {
+		t.Fatalf("EnqueueBatch: expected %d errors, actual %d", len(jobs), len(errs))
+	}
+	for i, err := range errs[:len(errs)-1] {
+		if err != nil {
+			t.Errorf("EnqueueBatch: job %d error %s", i, err)
+		}
+	}
+	if errs[len(errs)-1] == nil {
+		t.Errorf("EnqueueBatch: expected error for unmarshalable job")
+	}
+
+	for queue, expected := range map[string]int{"batchA": 503, "batchB": 502} {
+		actual, err := redis.Int(conn.Do("LLEN", fmt.Sprintf("%squeue:%s", workerSettings.Namespace, queue)))
+		if err != nil || actual != expected {
+			t.Errorf("EnqueueBatch: expected %d jobs on %s, actual %d (err %v)", expected, queue, actual, err)
+		}
+	}
+
+	first, err := redis.Bytes(conn.Do("LINDEX", fmt.Sprintf("%squeue:batchB", workerSettings.Namespace), 0))
+	if err != nil {
+		t.Fatalf("LINDEX: error %s", err)
+	}
+	if expected := fmt.Sprintf(`{"class":"Batched","args":[1],"id":"%s"`, jobs[1].Payload.ID); string(first[:len(expected)]) != expected {
+		t.Errorf("EnqueueBatch: expected first job on batchB to start with %s, actual %s", expected, first)
+	}
+}
+
+func TestEnqueueBatchAfterUnreadReplies(t *testing.T) {
+	workerSettings.Queues = []string{"batchA"}
+	workerSettings.Connections = 1
+	defer func() { workerSettings.Connections = 2 }()
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	stale := fmt.Sprintf("%sbatchStale", workerSettings.Namespace)
+	conn.Do("SET", stale, "not a number")
+	conn.Do("DEL", queueKey("batchA"))
+	conn.Send("INCR", stale)
+	conn.Flush()
+	PutConn(conn)
+
+	jobs := []*Job{{Queue: "batchA", Payload: Payload{Class: "Batched", Args: []interface{}{}}}}
+	for i, err := range EnqueueBatch(jobs) {
+		if err != nil {
+			t.Errorf("EnqueueBatch: job %d error %s", i, err)
+		}
+	}
+
+	conn, err = GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+	if n, _ := redis.Int(conn.Do("LLEN", queueKey("batchA"))); n != 1 {
+		t.Errorf("EnqueueBatch: expected 1 job on batchA, actual %d", n)
+	}
+}
@@ -1,6 +1,7 @@
 package goworker
 
 import (
+	"errors"
 	"time"
 )
 
@@ -13,3 +14,17 @@ type failure struct {
 	Worker    *worker   `json:"worker"`
 	Queue     string    `json:"queue"`
 }
+
+// exceptionName returns the exception recorded in the
+// failure for err, which tells failures that never reached
+// the worker function apart from errors it returned.
+func exceptionName(err error) string {
+	var decodeError *DecodeError
+	if errors.As(err, &decodeError) {
+		return "DecodeError"
+	}
+	if errors.Is(err, ErrPoisonJob) {
+		return "PoisonJob"
+	}
+	return "Error"
+}
@@ -0,0 +1,12 @@
+//go:build !goworker_noflags
+// +build !goworker_noflags
+
+package goworker
+
+import (
+	"flag"
+)
+
+func init() {
+	RegisterFlags(flag.CommandLine)
+}
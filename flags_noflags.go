@@ -0,0 +1,15 @@
+//go:build goworker_noflags
+// +build goworker_noflags
+
+package goworker
+
+import (
+	"flag"
+)
+
+// With the goworker_noflags tag the flags are kept on a
+// private flag set, which is never parsed but still supplies
+// the defaults and reads the config file and environment.
+func init() {
+	RegisterFlags(flag.NewFlagSet("goworker", flag.ContinueOnError))
+}
@@ -1,10 +1,15 @@
 module github.com/benmanns/goworker
 
-go 1.14
+go 1.18
 
 require (
 	github.com/cihub/seelog v0.0.0-20140730094913-72ae425987bc
 	github.com/gomodule/redigo v1.8.2
 	golang.org/x/net v0.0.0-20200822124328-c89045814202
-	vitess.io/vitess v3.0.0-rc.3.0.20181212200900-e2c5239f54d1+incompatible
+)
+
+require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/mattn/go-sqlite3 v1.14.16
+	gopkg.in/yaml.v3 v3.0.1
 )
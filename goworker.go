@@ -2,44 +2,76 @@ package goworker
 
 import (
 	"os"
-	"strconv"
 	"sync"
 	"time"
 
 	"golang.org/x/net/context"
 
 	"github.com/cihub/seelog"
-	"vitess.io/vitess/go/pools"
 )
 
 var (
 	logger      seelog.LoggerInterface
-	pool        *pools.ResourcePool
-	ctx         context.Context
+	pool        *connPool
 	initMutex   sync.Mutex
 	initialized bool
 )
 
 var workerSettings WorkerSettings
 
+// settingsFromCode records that SetSettings was called, so
+// that Init leaves its settings to the command line alone.
+var settingsFromCode bool
+
 type WorkerSettings struct {
-	QueuesString   string
-	Queues         queuesFlag
-	IntervalFloat  float64
-	Interval       intervalFlag
-	Concurrency    int
-	Connections    int
-	URI            string
-	Namespace      string
-	ExitOnComplete bool
-	IsStrict       bool
-	UseNumber      bool
-	SkipTLSVerify  bool
-	TLSCertPath    string
+	ConfigFile           string
+	QueuesString         string
+	Queues               queuesFlag
+	IntervalFloat        float64
+	Interval             intervalFlag
+	Concurrency          int
+	ConcurrencyKey       string
+	Connections          int
+	URI                  string
+	Namespace            string
+	ExitOnComplete       bool
+	IsStrict             bool
+	QueueWeights         map[string]int
+	QueueSelector        QueueSelector
+	PriorityQueuesString string
+	PriorityQueues       []string
+	SidekiqQueuesString  string
+	SidekiqQueues        []string
+	SidekiqNamespace     string
+	UseNumber            bool
+	SkipTLSVerify        bool
+	TLSCertPath          string
+	TLSClientCertPath    string
+	TLSClientKeyPath     string
+	TLSServerName        string
+	TLSMinVersion        string
+	Username             string
+	Password             string
+	StatusTTL            int
+	HashTag              bool
+	PoolTimeout          time.Duration
+	PoolMaxLifetime      time.Duration
+	PoolIdleTimeout      time.Duration
+	UnknownClassPolicy   string
+	UnknownClassQueue    string
+	UnknownClassRequeues int
+	PoisonThreshold      int
 }
 
+// SetSettings replaces the settings used by Init and Work.
+// Like those from flags, they are completed from QueuesString
+// and IntervalFloat and checked with Validate by Init. Init
+// then reads no GOWORKER_ environment variables or config
+// file, so settings made in code are overridden only by flags
+// given on the command line.
 func SetSettings(settings WorkerSettings) {
 	workerSettings = settings
+	settingsFromCode = true
 }
 
 // Init initializes the goworker process. This will be
@@ -59,9 +91,13 @@ func Init() error {
 		if err := flags(); err != nil {
 			return err
 		}
-		ctx = context.Background()
-
-		pool = newRedisPool(workerSettings.URI, workerSettings.Connections, workerSettings.Connections, time.Minute)
+		if err := workerSettings.normalize(); err != nil {
+			return err
+		}
+		if err := workerSettings.Validate(); err != nil {
+			return err
+		}
+		pool = newRedisPool(workerSettings.URI, workerSettings.Connections, workerSettings.PoolTimeout, workerSettings.PoolMaxLifetime, workerSettings.PoolIdleTimeout)
 
 		initialized = true
 	}
@@ -74,21 +110,59 @@ func Init() error {
 // connection will cause concurrent worker functions to lock
 // while they wait for an available connection. Expect this
 // API to change drastically.
+//
+// A connection that has been idle is checked with PING
+// before it is returned, and replaced if it does not answer.
 func GetConn() (*RedisConn, error) {
-	resource, err := pool.Get(ctx)
+	return GetConnContext(context.Background())
+}
 
-	if err != nil {
-		return nil, err
+// GetConnContext is like GetConn, but stops waiting for a
+// connection when ctx is done. Either way it waits no longer
+// than the -pool-timeout flag.
+func GetConnContext(ctx context.Context) (*RedisConn, error) {
+	for {
+		conn, err := pool.get(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if conn.healthy() {
+			return conn, nil
+		}
+		logger.Infof("Discarding broken Redis connection")
+		conn.pool.discard(conn)
 	}
-	return resource.(*RedisConn), nil
 }
 
 // PutConn puts a connection back into the connection pool.
 // Run this as soon as you finish using a connection that
-// you got from GetConn. Expect this API to change
-// drastically.
+// you got from GetConn. Commands still queued with Send are
+// flushed and their replies read first, so the next user of
+// the connection does not receive them. A connection that
+// has failed, or whose server has become a replica, is
+// closed and replaced by a new one when next needed. Expect
+// this API to change drastically.
 func PutConn(conn *RedisConn) {
-	pool.Put(conn)
+	if conn == nil {
+		return
+	}
+	if _, err := conn.flush(); err != nil {
+		logger.Errorf("Error in pipelined Redis command: %v", err)
+	}
+	if conn.broken() {
+		conn.pool.discard(conn)
+		return
+	}
+	conn.lastUsed = time.Now()
+	conn.pool.put(conn)
+}
+
+// GetPoolStats returns statistics of the Redis connection
+// pool, such as how many connections are in use and how
+// long callers have waited for one.
+func GetPoolStats() PoolStats {
+	return pool.stats()
 }
 
 // Close cleans up resources initialized by goworker. This
@@ -106,7 +180,7 @@ func Close() {
 	initMutex.Lock()
 	defer initMutex.Unlock()
 	if initialized {
-		pool.Close()
+		pool.close()
 		initialized = false
 	}
 }
@@ -115,7 +189,9 @@ func Close() {
 // the return value. Work will take over the Go executable
 // and will run until a QUIT, INT, or TERM signal is
 // received, or until the queues are empty if the
-// -exit-on-complete flag is set.
+// -exit-on-complete flag is set. While it runs, the number
+// of workers may be changed with SetConcurrency, TTIN and
+// TTOU signals, or the -concurrency-key Redis setting.
 func Work() error {
 	err := Init()
 	if err != nil {
@@ -123,9 +199,13 @@ func Work() error {
 	}
 	defer Close()
 
+	if len(workerSettings.Queues) == 0 {
+		return errorEmptyQueues
+	}
+
 	quit := signals()
 
-	poller, err := newPoller(workerSettings.Queues, workerSettings.IsStrict)
+	poller, err := newPoller(workerSettings.Queues, newQueueSelector(&workerSettings))
 	if err != nil {
 		return err
 	}
@@ -134,17 +214,23 @@ func Work() error {
 		return err
 	}
 
-	var monitor sync.WaitGroup
+	scaler := newScaler(jobs, workerSettings.Queues)
+	if err := scaler.resize(concurrency()); err != nil {
+		return err
+	}
+	setActiveScaler(scaler)
+	defer setActiveScaler(nil)
 
-	for id := 0; id < workerSettings.Concurrency; id++ {
-		worker, err := newWorker(strconv.Itoa(id), workerSettings.Queues)
-		if err != nil {
-			return err
-		}
-		worker.work(jobs, &monitor)
+	stopScale := notifyScale(scaler)
+	defer stopScale()
+
+	if workerSettings.ConcurrencyKey != "" {
+		done := make(chan struct{})
+		defer close(done)
+		go scaler.watch(workerSettings.ConcurrencyKey, time.Duration(workerSettings.Interval), done)
 	}
 
-	monitor.Wait()
+	scaler.wait()
 
 	return nil
 }
@@ -0,0 +1,384 @@
+package goworker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	errorInvalidWorkerName = errors.New("invalid worker name")
+	errorFailureNotFound   = errors.New("no failure at that index")
+)
+
+// QueueSize is the name and number of pending jobs of a
+// queue registered in the queues set.
+type QueueSize struct {
+	Name string
+	Size int64
+}
+
+// WorkerInfo describes a worker registered in the workers
+// set, along with the job it is running, if any.
+type WorkerInfo struct {
+	Name      string
+	Hostname  string
+	Pid       int
+	ID        string
+	Queues    []string
+	Started   string
+	Processed int64
+	Failed    int64
+	Job       *Job
+	RunAt     time.Time
+}
+
+// FailedJob is an entry of the failed list as written by
+// goworker or Resque. Payload is kept as raw JSON so that
+// retrying a job requeues it exactly as it was enqueued.
+type FailedJob struct {
+	FailedAt  string          `json:"failed_at"`
+	Payload   json.RawMessage `json:"payload"`
+	Exception string          `json:"exception"`
+	Error     string          `json:"error"`
+	Backtrace []string        `json:"backtrace"`
+	Worker    string          `json:"worker"`
+	Queue     string          `json:"queue"`
+	RetriedAt string          `json:"retried_at,omitempty"`
+}
+
+// Stats summarizes the state of the namespace in the same
+// way as Resque.info.
+type Stats struct {
+	Processed int64
+	Failed    int64
+	Pending   int64
+	Queues    int
+	Workers   int
+	Working   int
+}
+
+// QueueSizes returns every queue in the queues set with its
+// number of pending jobs, sorted by name. The size of a
+// queue includes jobs waiting in its priority queue.
+func QueueSizes() ([]QueueSize, error) {
+	if err := Init(); err != nil {
+		return nil, err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return nil, err
+	}
+	defer PutConn(conn)
+
+	names, err := redis.Strings(conn.Do("SMEMBERS", fmt.Sprintf("%squeues", workerSettings.Namespace)))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		conn.Send("LLEN", fmt.Sprintf("%squeue:%s", workerSettings.Namespace, name))
+		conn.Send("ZCARD", fmt.Sprintf("%spqueue:%s", workerSettings.Namespace, name))
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+
+	queues := make([]QueueSize, len(names))
+	for i, name := range names {
+		size, err := redis.Int64(conn.Receive())
+		if err != nil {
+			return nil, err
+		}
+		prioritized, err := redis.Int64(conn.Receive())
+		if err != nil {
+			return nil, err
+		}
+		queues[i] = QueueSize{Name: name, Size: size + prioritized}
+	}
+	return queues, nil
+}
+
+// Workers returns every worker in the workers set, sorted by
+// name.
+func Workers() ([]*WorkerInfo, error) {
+	if err := Init(); err != nil {
+		return nil, err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return nil, err
+	}
+	defer PutConn(conn)
+
+	names, err := redis.Strings(conn.Do("SMEMBERS", fmt.Sprintf("%sworkers", workerSettings.Namespace)))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	infos := make([]*WorkerInfo, 0, len(names))
+	for _, name := range names {
+		info, err := parseWorkerName(name)
+		if err != nil {
+			logger.Warnf("Skipping worker %q: %v", name, err)
+			continue
+		}
+
+		values, err := redis.Values(conn.Do("MGET",
+			fmt.Sprintf("%sworker:%s", workerSettings.Namespace, name),
+			fmt.Sprintf("%sworker:%s:started", workerSettings.Namespace, name),
+			fmt.Sprintf("%sstat:processed:%s", workerSettings.Namespace, name),
+			fmt.Sprintf("%sstat:failed:%s", workerSettings.Namespace, name),
+		))
+		if err != nil {
+			return nil, err
+		}
+
+		if buffer, _ := redis.Bytes(values[0], nil); buffer != nil {
+			var work work
+			if err := json.Unmarshal(buffer, &work); err != nil {
+				logger.Warnf("Invalid job data for worker %q: %v", name, err)
+			} else {
+				info.Job = &Job{Queue: work.Queue, Payload: work.Payload}
+				info.RunAt = work.RunAt
+			}
+		}
+		info.Started, _ = redis.String(values[1], nil)
+		info.Processed, _ = redis.Int64(values[2], nil)
+		info.Failed, _ = redis.Int64(values[3], nil)
+
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// parseWorkerName splits a worker name of the form
+// hostname:pid-id:queues into its parts.
+func parseWorkerName(name string) (*WorkerInfo, error) {
+	parts := strings.SplitN(name, ":", 3)
+	if len(parts) != 3 {
+		return nil, errorInvalidWorkerName
+	}
+
+	pidAndID := strings.SplitN(parts[1], "-", 2)
+	pid, err := strconv.Atoi(pidAndID[0])
+	if err != nil {
+		return nil, errorInvalidWorkerName
+	}
+
+	info := &WorkerInfo{
+		Name:     name,
+		Hostname: parts[0],
+		Pid:      pid,
+	}
+	if len(pidAndID) == 2 {
+		info.ID = pidAndID[1]
+	}
+	if parts[2] != "" {
+		info.Queues = strings.Split(parts[2], ",")
+	}
+	return info, nil
+}
+
+// Failures returns up to count entries of the failed list,
+// starting at index start. A count of zero or less returns
+// every entry from start on.
+func Failures(start, count int) ([]*FailedJob, error) {
+	if err := Init(); err != nil {
+		return nil, err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return nil, err
+	}
+	defer PutConn(conn)
+
+	stop := start + count - 1
+	if count <= 0 {
+		stop = -1
+	}
+
+	items, err := redis.ByteSlices(conn.Do("LRANGE", fmt.Sprintf("%sfailed", workerSettings.Namespace), start, stop))
+	if err != nil {
+		return nil, err
+	}
+
+	failures := make([]*FailedJob, len(items))
+	for i, item := range items {
+		failures[i] = &FailedJob{}
+		if err := json.Unmarshal(item, failures[i]); err != nil {
+			return nil, err
+		}
+	}
+	return failures, nil
+}
+
+// FailureCount returns the length of the failed list.
+func FailureCount() (int64, error) {
+	if err := Init(); err != nil {
+		return 0, err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return 0, err
+	}
+	defer PutConn(conn)
+
+	return redis.Int64(conn.Do("LLEN", fmt.Sprintf("%sfailed", workerSettings.Namespace)))
+}
+
+// RetryFailure requeues the job of the failure at index onto
+// its original queue and marks the failure as retried, as
+// Resque does.
+func RetryFailure(index int) error {
+	if err := Init(); err != nil {
+		return err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return err
+	}
+	defer PutConn(conn)
+
+	key := fmt.Sprintf("%sfailed", workerSettings.Namespace)
+	item, err := redis.Bytes(conn.Do("LINDEX", key, index))
+	if err == redis.ErrNil {
+		return errorFailureNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(item, &fields); err != nil {
+		return err
+	}
+	var queue string
+	if err := json.Unmarshal(fields["queue"], &queue); err != nil {
+		return err
+	}
+	var payload Payload
+	if err := json.Unmarshal(fields["payload"], &payload); err != nil {
+		return err
+	}
+
+	retriedAt, err := json.Marshal(time.Now().UTC().Format("2006/01/02 15:04:05 MST"))
+	if err != nil {
+		return err
+	}
+	fields["retried_at"] = retriedAt
+	buffer, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	command, args := pushCommand(queue, &payload, []byte(fields["payload"]))
+	conn.Send(command, args...)
+	conn.Send("SADD", queuesKey(queue), queue)
+	conn.Send("LSET", key, index, buffer)
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := conn.Receive(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveFailure deletes the failure at index from the failed
+// list.
+func RemoveFailure(index int) error {
+	if err := Init(); err != nil {
+		return err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return err
+	}
+	defer PutConn(conn)
+
+	key := fmt.Sprintf("%sfailed", workerSettings.Namespace)
+	if _, err := conn.Do("LSET", key, index, "__delete__"); err != nil {
+		if err.Error() == "ERR index out of range" {
+			return errorFailureNotFound
+		}
+		return err
+	}
+	_, err = conn.Do("LREM", key, 1, "__delete__")
+	return err
+}
+
+// ClearFailures deletes the failed list.
+func ClearFailures() error {
+	if err := Init(); err != nil {
+		return err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return err
+	}
+	defer PutConn(conn)
+
+	_, err = conn.Do("DEL", fmt.Sprintf("%sfailed", workerSettings.Namespace))
+	return err
+}
+
+// GetStats returns processed and failed totals along with
+// counts of pending jobs, queues and workers.
+func GetStats() (*Stats, error) {
+	queues, err := QueueSizes()
+	if err != nil {
+		return nil, err
+	}
+	workers, err := Workers()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return nil, err
+	}
+	defer PutConn(conn)
+
+	values, err := redis.Values(conn.Do("MGET",
+		fmt.Sprintf("%sstat:processed", workerSettings.Namespace),
+		fmt.Sprintf("%sstat:failed", workerSettings.Namespace),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{
+		Queues:  len(queues),
+		Workers: len(workers),
+	}
+	stats.Processed, _ = redis.Int64(values[0], nil)
+	stats.Failed, _ = redis.Int64(values[1], nil)
+	for _, queue := range queues {
+		stats.Pending += queue.Size
+	}
+	for _, worker := range workers {
+		if worker.Job != nil {
+			stats.Working++
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,141 @@
+package goworker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var parseWorkerNameTests = []struct {
+	name     string
+	expected *WorkerInfo
+	err      error
+}{
+	{
+		"hostname:12345-123:high,low",
+		&WorkerInfo{
+			Name:     "hostname:12345-123:high,low",
+			Hostname: "hostname",
+			Pid:      12345,
+			ID:       "123",
+			Queues:   []string{"high", "low"},
+		},
+		nil,
+	},
+	{
+		"hostname:12345-poller:",
+		&WorkerInfo{
+			Name:     "hostname:12345-poller:",
+			Hostname: "hostname",
+			Pid:      12345,
+			ID:       "poller",
+		},
+		nil,
+	},
+	{
+		"hostname:12345:high",
+		&WorkerInfo{
+			Name:     "hostname:12345:high",
+			Hostname: "hostname",
+			Pid:      12345,
+			Queues:   []string{"high"},
+		},
+		nil,
+	},
+	{
+		"hostname",
+		nil,
+		errorInvalidWorkerName,
+	},
+	{
+		"hostname:abc-1:high",
+		nil,
+		errorInvalidWorkerName,
+	},
+}
+
+func TestParseWorkerName(t *testing.T) {
+	for _, tt := range parseWorkerNameTests {
+		actual, err := parseWorkerName(tt.name)
+		if err != tt.err {
+			t.Errorf("parseWorkerName(%s): expected err %v, actual err %v", tt.name, tt.err, err)
+		}
+		if fmt.Sprint(actual) != fmt.Sprint(tt.expected) {
+			t.Errorf("parseWorkerName(%s): expected %v, actual %v", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestRetryAndRemoveFailure(t *testing.T) {
+	workerSettings.Queues = []string{"inspectQueue"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	if err := ClearFailures(); err != nil {
+		t.Fatalf("ClearFailures: error %s", err)
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	conn.Do("DEL", fmt.Sprintf("%squeue:inspectQueue", workerSettings.Namespace))
+	w := &worker{}
+	for i := 0; i < 2; i++ {
+		job := &Job{Queue: "inspectQueue", Payload: Payload{Class: "Failing", Args: []interface{}{i}}}
+		w.fail(conn, job, fmt.Errorf("failure %d", i))
+	}
+	var failed map[string]json.RawMessage
+	buffer, err := redis.Bytes(conn.Do("LINDEX", fmt.Sprintf("%sfailed", workerSettings.Namespace), 1))
+	if err != nil {
+		t.Fatalf("LINDEX: error %s", err)
+	}
+	if err := json.Unmarshal(buffer, &failed); err != nil {
+		t.Fatalf("Unmarshal: error %s", err)
+	}
+
+	if err := RetryFailure(1); err != nil {
+		t.Errorf("RetryFailure: error %s", err)
+	}
+	retried, err := redis.Bytes(conn.Do("LINDEX", queueKey("inspectQueue"), 0))
+	if err != nil {
+		t.Fatalf("LINDEX: error %s", err)
+	}
+	if !bytes.Equal(retried, failed["payload"]) {
+		t.Errorf("RetryFailure: expected %s pushed, actual %s", failed["payload"], retried)
+	}
+	var payload Payload
+	if err := json.Unmarshal(retried, &payload); err != nil || payload.Class != "Failing" || fmt.Sprint(payload.Args) != "[1]" {
+		t.Errorf("RetryFailure: expected Failing with args [1], actual %+v (err %v)", payload, err)
+	}
+	PutConn(conn)
+	if err := RemoveFailure(0); err != nil {
+		t.Errorf("RemoveFailure: error %s", err)
+	}
+	if err := RemoveFailure(5); err != errorFailureNotFound {
+		t.Errorf("RemoveFailure: expected err %v, actual err %v", errorFailureNotFound, err)
+	}
+
+	failures, err := Failures(0, 10)
+	if err != nil {
+		t.Fatalf("Failures: error %s", err)
+	}
+	if len(failures) != 1 || failures[0].Error != "failure 1" || failures[0].RetriedAt == "" {
+		t.Errorf("Failures: expected retried failure 1, actual %+v", failures)
+	}
+
+	queues, err := QueueSizes()
+	if err != nil {
+		t.Fatalf("QueueSizes: error %s", err)
+	}
+	for _, queue := range queues {
+		if queue.Name == "inspectQueue" && queue.Size != 1 {
+			t.Errorf("QueueSizes: expected 1 job in inspectQueue, actual %d", queue.Size)
+		}
+	}
+}
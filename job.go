@@ -3,4 +3,12 @@ package goworker
 type Job struct {
 	Queue   string
 	Payload Payload
+
+	// raw is the payload as it was read from Redis, kept so
+	// that typed worker functions decode their arguments
+	// from the original JSON rather than from Args.
+	raw []byte
+	// sidekiq is the job hash of a job read from a Sidekiq
+	// queue.
+	sidekiq map[string]interface{}
 }
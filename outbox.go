@@ -0,0 +1,181 @@
+package goworker
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Outbox enqueues jobs as part of a database transaction.
+// Jobs are written to an outbox table through the
+// transaction, so they exist only if it commits, and Relay
+// later moves committed rows to Redis with Enqueue. The
+// table needs an auto-incrementing integer id and text
+// queue and payload columns, for example in PostgreSQL
+//
+//	CREATE TABLE goworker_outbox (
+//		id      BIGSERIAL PRIMARY KEY,
+//		queue   TEXT NOT NULL,
+//		payload TEXT NOT NULL
+//	);
+//
+// or in SQLite
+//
+//	CREATE TABLE goworker_outbox (
+//		id      INTEGER PRIMARY KEY AUTOINCREMENT,
+//		queue   TEXT NOT NULL,
+//		payload TEXT NOT NULL
+//	);
+//
+// Delivery is at least once: a row is deleted only after
+// its job is enqueued, so a crash in between, or two relays
+// running at the same time, can enqueue a job twice. Use
+// the job ID to detect duplicates.
+type Outbox struct {
+	DB    *sql.DB
+	Table string
+	// Numbered selects $1-style placeholders, as used by
+	// PostgreSQL, instead of ?.
+	Numbered bool
+	// BatchSize is the largest number of rows moved by one
+	// call to RelayOnce, 100 if it is not positive.
+	BatchSize int
+	// Interval is the wait between calls to RelayOnce in
+	// Relay when the outbox is empty, a second if it is not
+	// positive.
+	Interval time.Duration
+}
+
+// NewOutbox returns an outbox stored in table of db that
+// relays up to 100 jobs at a time and checks for new jobs
+// every second.
+func NewOutbox(db *sql.DB, table string) *Outbox {
+	return &Outbox{
+		DB:        db,
+		Table:     table,
+		BatchSize: 100,
+		Interval:  time.Second,
+	}
+}
+
+func (o *Outbox) batchSize() int {
+	if o.BatchSize <= 0 {
+		return 100
+	}
+	return o.BatchSize
+}
+
+func (o *Outbox) interval() time.Duration {
+	if o.Interval <= 0 {
+		return time.Second
+	}
+	return o.Interval
+}
+
+func (o *Outbox) placeholder(n int) string {
+	if o.Numbered {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// Enqueue writes job to the outbox through tx. The job is
+// given its ID and enqueued time now, so they are the same
+// once it reaches Redis.
+func (o *Outbox) Enqueue(tx *sql.Tx, job *Job) error {
+	if err := job.Payload.stamp(); err != nil {
+		return err
+	}
+
+	buffer, err := json.Marshal(job.Payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("INSERT INTO %s (queue, payload) VALUES (%s, %s)", o.Table, o.placeholder(1), o.placeholder(2)), job.Queue, string(buffer))
+	return err
+}
+
+// RelayOnce moves up to BatchSize committed jobs from the
+// outbox to Redis, oldest first, and returns how many it
+// moved. A row whose payload cannot be decoded is logged and
+// deleted, so that it does not hold up the rows behind it.
+func (o *Outbox) RelayOnce(ctx context.Context) (int, error) {
+	rows, err := o.DB.QueryContext(ctx, fmt.Sprintf("SELECT id, queue, payload FROM %s ORDER BY id LIMIT %d", o.Table, o.batchSize()))
+	if err != nil {
+		return 0, err
+	}
+
+	type row struct {
+		id      int64
+		queue   string
+		payload string
+	}
+	var pending []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.queue, &r.payload); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, r := range pending {
+		job := &Job{Queue: r.queue}
+
+		decoder := json.NewDecoder(bytes.NewReader([]byte(r.payload)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&job.Payload); err != nil {
+			logger.Criticalf("Deleting outbox %s row %d for queue %s, whose payload cannot be decoded: %v: %s", o.Table, r.id, r.queue, err, r.payload)
+			if err := o.delete(ctx, r.id); err != nil {
+				return moved, err
+			}
+			continue
+		}
+
+		if err := Enqueue(job); err != nil {
+			return moved, err
+		}
+		if err := o.delete(ctx, r.id); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+func (o *Outbox) delete(ctx context.Context, id int64) error {
+	_, err := o.DB.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = %s", o.Table, o.placeholder(1)), id)
+	return err
+}
+
+// Relay calls RelayOnce until ctx is done, waiting Interval
+// whenever the outbox is empty or an error occurs.
+func (o *Outbox) Relay(ctx context.Context) error {
+	for {
+		n, err := o.RelayOnce(ctx)
+		if err != nil {
+			logger.Errorf("Error relaying outbox %s: %v", o.Table, err)
+		}
+
+		if err != nil || n < o.batchSize() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(o.interval()):
+			}
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
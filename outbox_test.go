@@ -0,0 +1,181 @@
+package goworker
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/net/context"
+)
+
+func TestOutbox(t *testing.T) {
+	workerSettings.Queues = []string{"outboxQueue"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open: error %s", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("CREATE TABLE outbox (id INTEGER PRIMARY KEY AUTOINCREMENT, queue TEXT NOT NULL, payload TEXT NOT NULL)"); err != nil {
+		t.Fatalf("CREATE TABLE: error %s", err)
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+	key := fmt.Sprintf("%squeue:outboxQueue", workerSettings.Namespace)
+	conn.Do("DEL", key)
+
+	outbox := NewOutbox(db, "outbox")
+
+	for _, commit := range []bool{true, false, true} {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Begin: error %s", err)
+		}
+		job := &Job{Queue: "outboxQueue", Payload: Payload{Class: "Outboxed", Args: []interface{}{commit, 9007199254740993}}}
+		if err := outbox.Enqueue(tx, job); err != nil {
+			t.Fatalf("Outbox: enqueue error %s", err)
+		}
+		if commit {
+			err = tx.Commit()
+		} else {
+			err = tx.Rollback()
+		}
+		if err != nil {
+			t.Fatalf("Outbox: commit %v error %s", commit, err)
+		}
+	}
+
+	n, err := outbox.RelayOnce(context.Background())
+	if err != nil || n != 2 {
+		t.Errorf("Outbox: expected 2 jobs relayed, actual %d (err %v)", n, err)
+	}
+	n, err = outbox.RelayOnce(context.Background())
+	if err != nil || n != 0 {
+		t.Errorf("Outbox: expected empty outbox, actual %d (err %v)", n, err)
+	}
+
+	payloads, err := redis.Strings(conn.Do("LRANGE", key, 0, -1))
+	if err != nil {
+		t.Fatalf("LRANGE: error %s", err)
+	}
+	if len(payloads) != 2 {
+		t.Fatalf("Outbox: expected 2 jobs in Redis, actual %v", payloads)
+	}
+	for _, payload := range payloads {
+		if expected := `{"class":"Outboxed","args":[true,9007199254740993],"id":"`; payload[:len(expected)] != expected {
+			t.Errorf("Outbox: expected job to start with %s, actual %s", expected, payload)
+		}
+	}
+}
+
+func TestOutboxKeepsRowWhenPushFails(t *testing.T) {
+	workerSettings.Queues = []string{"outboxBroken"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open: error %s", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("CREATE TABLE outbox (id INTEGER PRIMARY KEY AUTOINCREMENT, queue TEXT NOT NULL, payload TEXT NOT NULL)"); err != nil {
+		t.Fatalf("CREATE TABLE: error %s", err)
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	key := queueKey("outboxBroken")
+	defer PutConn(conn)
+	conn.Do("SET", key, "not a list")
+	defer conn.Do("SREM", fmt.Sprintf("%squeues", workerSettings.Namespace), "outboxBroken")
+	defer conn.Do("DEL", key)
+
+	job := &Job{Queue: "outboxBroken", Payload: Payload{Class: "Outboxed", Args: []interface{}{}}}
+	if err := Enqueue(job); err == nil {
+		t.Errorf("Enqueue: expected the WRONGTYPE error of the push")
+	}
+
+	outbox := NewOutbox(db, "outbox")
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: error %s", err)
+	}
+	if err := outbox.Enqueue(tx, job); err != nil {
+		t.Fatalf("Outbox: enqueue error %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Outbox: commit error %s", err)
+	}
+
+	if n, err := outbox.RelayOnce(context.Background()); err == nil || n != 0 {
+		t.Errorf("Outbox: expected the push to fail, actual %d relayed (err %v)", n, err)
+	}
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM outbox").Scan(&rows); err != nil || rows != 1 {
+		t.Errorf("Outbox: expected the row to stay, actual %d rows (err %v)", rows, err)
+	}
+}
+
+func TestOutboxDefaultsAndUndecodableRows(t *testing.T) {
+	workerSettings.Queues = []string{"outboxQueue"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open: error %s", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("CREATE TABLE outbox (id INTEGER PRIMARY KEY AUTOINCREMENT, queue TEXT NOT NULL, payload TEXT NOT NULL)"); err != nil {
+		t.Fatalf("CREATE TABLE: error %s", err)
+	}
+	if _, err := db.Exec(`INSERT INTO outbox (queue, payload) VALUES ('outboxQueue', 'not json'), ('outboxQueue', '{"class":"Outboxed","args":[]}')`); err != nil {
+		t.Fatalf("INSERT: error %s", err)
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+	conn.Do("DEL", queueKey("outboxQueue"))
+
+	outbox := &Outbox{DB: db, Table: "outbox"}
+	if n, err := outbox.RelayOnce(context.Background()); err != nil || n != 1 {
+		t.Errorf("Outbox: expected 1 job relayed past the undecodable row, actual %d (err %v)", n, err)
+	}
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM outbox").Scan(&rows); err != nil || rows != 0 {
+		t.Errorf("Outbox: expected no rows left, actual %d (err %v)", rows, err)
+	}
+	if n, _ := redis.Int(conn.Do("LLEN", queueKey("outboxQueue"))); n != 1 {
+		t.Errorf("Outbox: expected 1 job in Redis, actual %d", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := outbox.Relay(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Outbox: expected Relay to wait for the deadline, actual %v", err)
+	}
+}
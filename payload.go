@@ -1,6 +1,63 @@
 package goworker
 
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Payload is the JSON object stored on a queue for each
+// job. Ruby Resque reads only class and args and ignores
+// the other keys, which goworker uses to identify and
+// describe the job across logs, retries and failures.
 type Payload struct {
 	Class string        `json:"class"`
 	Args  []interface{} `json:"args"`
+
+	// ID uniquely identifies the job. It is set by Enqueue
+	// and kept when the job is retried.
+	ID string `json:"id,omitempty"`
+	// EnqueuedAt is the time the job was first enqueued,
+	// in seconds since the Unix epoch.
+	EnqueuedAt float64 `json:"enqueued_at,omitempty"`
+	// Attempt is the number of times a worker has started
+	// the job, including the current run.
+	Attempt int `json:"attempt,omitempty"`
+	// Meta holds free-form metadata about the job.
+	Meta map[string]interface{} `json:"meta,omitempty"`
+	// Priority orders jobs on a priority queue, where jobs
+	// with a higher priority are worked first. It is
+	// ignored by ordinary queues.
+	Priority int `json:"priority,omitempty"`
+}
+
+// EnqueuedTime returns EnqueuedAt as a time.Time.
+func (p *Payload) EnqueuedTime() time.Time {
+	seconds := int64(p.EnqueuedAt)
+	return time.Unix(seconds, int64((p.EnqueuedAt-float64(seconds))*float64(time.Second)))
+}
+
+// stamp gives the payload an ID and enqueued time unless it
+// already has them.
+func (p *Payload) stamp() error {
+	if p.ID == "" {
+		id, err := newJobID()
+		if err != nil {
+			return err
+		}
+		p.ID = id
+	}
+	if p.EnqueuedAt == 0 {
+		p.EnqueuedAt = float64(time.Now().UnixNano()) / float64(time.Second)
+	}
+	return nil
+}
+
+// newJobID returns 24 random hexadecimal characters.
+func newJobID() (string, error) {
+	buffer := make([]byte, 12)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
 }
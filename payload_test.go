@@ -0,0 +1,53 @@
+package goworker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPayloadStamp(t *testing.T) {
+	p := &Payload{Class: "Stamped"}
+	if err := p.stamp(); err != nil {
+		t.Fatalf("Payload: stamp error %s", err)
+	}
+	if len(p.ID) != 24 {
+		t.Errorf("Payload: expected a 24 character ID, actual %q", p.ID)
+	}
+	if since := time.Since(p.EnqueuedTime()); since < -time.Second || since > time.Minute {
+		t.Errorf("Payload: expected enqueued time close to now, actual %v", p.EnqueuedTime())
+	}
+
+	id, enqueuedAt := p.ID, p.EnqueuedAt
+	if err := p.stamp(); err != nil {
+		t.Fatalf("Payload: stamp error %s", err)
+	}
+	if p.ID != id || p.EnqueuedAt != enqueuedAt {
+		t.Errorf("Payload: expected stamp to keep %s %f, actual %s %f", id, enqueuedAt, p.ID, p.EnqueuedAt)
+	}
+}
+
+var payloadMarshalTests = []struct {
+	p        Payload
+	expected string
+}{
+	{
+		Payload{Class: "Plain", Args: []interface{}{"a"}},
+		`{"class":"Plain","args":["a"]}`,
+	},
+	{
+		Payload{Class: "Full", Args: []interface{}{}, ID: "abc", EnqueuedAt: 1.5, Attempt: 2, Meta: map[string]interface{}{"k": "v"}},
+		`{"class":"Full","args":[],"id":"abc","enqueued_at":1.5,"attempt":2,"meta":{"k":"v"}}`,
+	},
+}
+
+func TestPayloadMarshal(t *testing.T) {
+	for _, tt := range payloadMarshalTests {
+		actual, err := json.Marshal(tt.p)
+		if err != nil {
+			t.Errorf("Payload(%#v): error %s", tt.p, err)
+		} else if string(actual) != tt.expected {
+			t.Errorf("Payload(%#v): expected %s, actual %s", tt.p, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package goworker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// poisonStartsTTL is how long the count of unfinished starts
+// of a job is kept after its last start.
+const poisonStartsTTL = 24 * time.Hour
+
+// ErrPoisonJob is the error a job fails with when it has
+// been started more than -poison-threshold times without
+// finishing, as happens when it crashes the process. It is
+// recorded in the quarantine list instead of the failed list,
+// with the exception PoisonJob, and the job is not run again.
+var ErrPoisonJob = errors.New("the job was started too many times without finishing")
+
+// startsKey returns the key counting the unfinished starts
+// of the job with the given ID.
+func startsKey(id string) string {
+	return fmt.Sprintf("%sstarts:%s", workerSettings.Namespace, id)
+}
+
+// countStart counts a start of job, returning an error
+// wrapping ErrPoisonJob if it has now been started more than
+// -poison-threshold times without finishing. Jobs without an
+// ID, such as those enqueued by Ruby Resque, are not counted.
+func countStart(conn *RedisConn, job *Job) error {
+	if workerSettings.PoisonThreshold == 0 || job.Payload.ID == "" {
+		return nil
+	}
+
+	key := startsKey(job.Payload.ID)
+	conn.Send("INCR", key)
+	conn.Send("EXPIRE", key, int(poisonStartsTTL/time.Second))
+	replies, err := redis.Values(conn.Do(""))
+	if err != nil {
+		return err
+	}
+	starts, err := redis.Int(replies[len(replies)-2], nil)
+	if err != nil {
+		return err
+	}
+
+	if starts > workerSettings.PoisonThreshold {
+		logger.Criticalf("Quarantining %s (job %s), which was started %d times without finishing", job.Payload.Class, job.Payload.ID, starts-1)
+		return fmt.Errorf("%w: %d starts", ErrPoisonJob, starts-1)
+	}
+	return nil
+}
+
+// finishStart forgets the unfinished starts of job once it
+// has finished.
+func finishStart(conn *RedisConn, job *Job) error {
+	if workerSettings.PoisonThreshold == 0 || job.Payload.ID == "" {
+		return nil
+	}
+	return conn.Send("DEL", startsKey(job.Payload.ID))
+}
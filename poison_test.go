@@ -0,0 +1,73 @@
+package goworker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestPoisonJob(t *testing.T) {
+	workerSettings.Queues = []string{"poisonQueue"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+	defer func() { workerSettings.PoisonThreshold = 3 }()
+	workerSettings.PoisonThreshold = 2
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+
+	quarantineKey := fmt.Sprintf("%squarantine", workerSettings.Namespace)
+	failedKey := fmt.Sprintf("%sfailed", workerSettings.Namespace)
+	job := &Job{Queue: "poisonQueue", Payload: Payload{Class: "Crasher", Args: []interface{}{}, ID: "poison"}}
+	conn.Do("DEL", startsKey(job.Payload.ID), quarantineKey, failedKey)
+
+	for start := 1; start <= 3; start++ {
+		err := countStart(conn, job)
+		if poisoned := errors.Is(err, ErrPoisonJob); poisoned != (start == 3) {
+			t.Errorf("countStart: start %d returned %v", start, err)
+		}
+	}
+
+	w, err := newWorker("1", workerSettings.Queues)
+	if err != nil {
+		t.Fatalf("newWorker: error %s", err)
+	}
+	w.finish(conn, job, countStart(conn, job))
+	conn.Do("")
+
+	if failed, _ := redis.Int(conn.Do("LLEN", failedKey)); failed != 0 {
+		t.Errorf("finish: expected nothing in the failed list, actual %d", failed)
+	}
+	buffer, err := redis.Bytes(conn.Do("LINDEX", quarantineKey, 0))
+	if err != nil {
+		t.Fatalf("finish: expected the job in the quarantine list, error %s", err)
+	}
+	var entry failure
+	json.Unmarshal(buffer, &entry)
+	if entry.Exception != "PoisonJob" || entry.Payload.ID != job.Payload.ID {
+		t.Errorf("finish: unexpected quarantine entry %s", buffer)
+	}
+
+	if exists, _ := redis.Bool(conn.Do("EXISTS", startsKey(job.Payload.ID))); exists {
+		t.Errorf("finish: expected the starts of the job to be cleared")
+	}
+	if err := countStart(conn, job); err != nil {
+		t.Errorf("countStart: expected a finished job to start afresh, actual %v", err)
+	}
+
+	workerSettings.PoisonThreshold = 0
+	conn.Do("DEL", startsKey(job.Payload.ID))
+	for start := 1; start <= 3; start++ {
+		if err := countStart(conn, job); err != nil {
+			t.Errorf("countStart: expected no quarantine with a threshold of 0, actual %v", err)
+		}
+	}
+}
@@ -9,32 +9,37 @@ import (
 
 type poller struct {
 	process
-	isStrict bool
+	selector      QueueSelector
+	sidekiqPolled time.Time
 }
 
-func newPoller(queues []string, isStrict bool) (*poller, error) {
+func newPoller(queues []string, selector QueueSelector) (*poller, error) {
 	process, err := newProcess("poller", queues)
 	if err != nil {
 		return nil, err
 	}
 	return &poller{
 		process:  *process,
-		isStrict: isStrict,
+		selector: selector,
 	}, nil
 }
 
 func (p *poller) getJob(conn *RedisConn) (*Job, error) {
-	for _, queue := range p.queues(p.isStrict) {
+	for _, queue := range p.selector.Select() {
 		logger.Debugf("Checking %s", queue)
 
-		reply, err := conn.Do("LPOP", fmt.Sprintf("%squeue:%s", workerSettings.Namespace, queue))
+		reply, err := popJob(conn, queue)
 		if err != nil {
 			return nil, err
 		}
 		if reply != nil {
 			logger.Debugf("Found job on %s", queue)
 
-			job := &Job{Queue: queue}
+			if isSidekiqQueue(queue) {
+				return decodeSidekiqJob(queue, reply.([]byte))
+			}
+
+			job := &Job{Queue: queue, raw: reply.([]byte)}
 
 			decoder := json.NewDecoder(bytes.NewReader(reply.([]byte)))
 			if workerSettings.UseNumber {
@@ -80,6 +85,7 @@ func (p *poller) poll(interval time.Duration, quit <-chan bool) (<-chan *Job, er
 			}
 		}()
 
+		var retry backoff
 		for {
 			select {
 			case <-quit:
@@ -87,16 +93,32 @@ func (p *poller) poll(interval time.Duration, quit <-chan bool) (<-chan *Job, er
 			default:
 				conn, err := GetConn()
 				if err != nil {
-					logger.Criticalf("Error on getting connection in poller %s: %v", p, err)
-					return
+					wait := retry.next()
+					logger.Criticalf("Error on getting connection in poller %s, retrying in %v: %v", p, wait, err)
+					if !sleep(wait, quit) {
+						return
+					}
+					continue
+				}
+
+				if len(workerSettings.SidekiqQueues) > 0 && time.Since(p.sidekiqPolled) >= sidekiqPollInterval {
+					p.sidekiqPolled = time.Now()
+					if err := enqueueDueSidekiqJobs(conn); err != nil {
+						logger.Criticalf("Error on %v enqueueing due Sidekiq jobs: %v", p, err)
+					}
 				}
 
 				job, err := p.getJob(conn)
 				if err != nil {
-					logger.Criticalf("Error on %v getting job from %v: %v", p, p.Queues, err)
 					PutConn(conn)
-					return
+					wait := retry.next()
+					logger.Criticalf("Error on %v getting job from %v, retrying in %v: %v", p, p.Queues, wait, err)
+					if !sleep(wait, quit) {
+						return
+					}
+					continue
 				}
+				retry.reset()
 				if job != nil {
 					conn.Send("INCR", fmt.Sprintf("%sstat:processed:%v", workerSettings.Namespace, p))
 					conn.Flush()
@@ -104,18 +126,19 @@ func (p *poller) poll(interval time.Duration, quit <-chan bool) (<-chan *Job, er
 					select {
 					case jobs <- job:
 					case <-quit:
-						buf, err := json.Marshal(job.Payload)
+						buf, err := encodeJob(job)
 						if err != nil {
 							logger.Criticalf("Error requeueing %v: %v", job, err)
 							return
 						}
-						conn, err := GetConn()
+						conn, err := getConnRetrying()
 						if err != nil {
-							logger.Criticalf("Error on getting connection in poller %s: %v", p, err)
+							logger.Criticalf("Error on getting connection in poller %s, losing %v: %v", p, job, err)
 							return
 						}
 
-						conn.Send("LPUSH", fmt.Sprintf("%squeue:%s", workerSettings.Namespace, job.Queue), buf)
+						command, args := requeueCommand(job.Queue, &job.Payload, buf)
+						conn.Send(command, args...)
 						conn.Flush()
 						PutConn(conn)
 						return
@@ -128,11 +151,8 @@ func (p *poller) poll(interval time.Duration, quit <-chan bool) (<-chan *Job, er
 					logger.Debugf("Sleeping for %v", interval)
 					logger.Debugf("Waiting for %v", p.Queues)
 
-					timeout := time.After(interval)
-					select {
-					case <-quit:
+					if !sleep(interval, quit) {
 						return
-					case <-timeout:
 					}
 				}
 			}
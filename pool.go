@@ -0,0 +1,229 @@
+package goworker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+var (
+	errorPoolClosed  = errors.New("the Redis connection pool is closed")
+	errorPoolTimeout = errors.New("timed out waiting for a Redis connection")
+)
+
+// PoolStats describes the Redis connection pool.
+type PoolStats struct {
+	Capacity     int
+	InUse        int
+	Idle         int
+	Waiters      int
+	WaitCount    int64
+	WaitDuration time.Duration
+	Timeouts     int64
+}
+
+// connPool lends up to capacity Redis connections. Returned
+// connections are kept for reuse until they have been idle
+// for idleTimeout or open for maxLifetime, where a zero
+// duration means no limit.
+type connPool struct {
+	dial        func() (*RedisConn, error)
+	timeout     time.Duration
+	maxLifetime time.Duration
+	idleTimeout time.Duration
+
+	slots chan struct{}
+	done  chan struct{}
+
+	mutex        sync.Mutex
+	idle         []*RedisConn
+	closed       bool
+	waiters      int
+	waitCount    int64
+	waitDuration time.Duration
+	timeouts     int64
+}
+
+func newConnPool(dial func() (*RedisConn, error), capacity int, timeout, maxLifetime, idleTimeout time.Duration) *connPool {
+	if capacity < 1 {
+		capacity = 1
+	}
+	p := &connPool{
+		dial:        dial,
+		timeout:     timeout,
+		maxLifetime: maxLifetime,
+		idleTimeout: idleTimeout,
+		slots:       make(chan struct{}, capacity),
+		done:        make(chan struct{}),
+	}
+	if idleTimeout > 0 {
+		go p.evict(idleTimeout / 2)
+	}
+	return p
+}
+
+// get borrows a connection, waiting for one to be returned
+// if all are in use, until ctx is done or the pool timeout
+// passes.
+func (p *connPool) get(ctx context.Context) (*RedisConn, error) {
+	if err := p.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		<-p.slots
+		return nil, errorPoolClosed
+	}
+	for len(p.idle) > 0 {
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if !p.expired(conn, time.Now()) {
+			p.mutex.Unlock()
+			return conn, nil
+		}
+		conn.Close()
+	}
+	p.mutex.Unlock()
+
+	conn, err := p.dial()
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	conn.pool = p
+	conn.createdAt = time.Now()
+	return conn, nil
+}
+
+// acquire takes one of the slots that bound the number of
+// connections lent at once.
+func (p *connPool) acquire(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	start := time.Now()
+	p.mutex.Lock()
+	p.waiters++
+	p.mutex.Unlock()
+
+	var timeout <-chan time.Time
+	if p.timeout > 0 {
+		timer := time.NewTimer(p.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = errorPoolTimeout
+	case <-p.done:
+		err = errorPoolClosed
+	}
+
+	p.mutex.Lock()
+	p.waiters--
+	p.waitCount++
+	p.waitDuration += time.Since(start)
+	if err != nil {
+		p.timeouts++
+	}
+	p.mutex.Unlock()
+	return err
+}
+
+// put returns a borrowed connection for reuse.
+func (p *connPool) put(conn *RedisConn) {
+	p.mutex.Lock()
+	if p.closed || p.expired(conn, time.Now()) {
+		conn.Close()
+	} else {
+		p.idle = append(p.idle, conn)
+	}
+	p.mutex.Unlock()
+	<-p.slots
+}
+
+// discard closes a borrowed connection instead of returning
+// it, so that a new one is dialed when next needed.
+func (p *connPool) discard(conn *RedisConn) {
+	conn.Close()
+	<-p.slots
+}
+
+// expired reports whether conn should be closed rather than
+// lent again.
+func (p *connPool) expired(conn *RedisConn, now time.Time) bool {
+	if p.maxLifetime > 0 && now.Sub(conn.createdAt) >= p.maxLifetime {
+		return true
+	}
+	if p.idleTimeout > 0 && !conn.lastUsed.IsZero() && now.Sub(conn.lastUsed) >= p.idleTimeout {
+		return true
+	}
+	return false
+}
+
+// evict closes expired idle connections every interval until
+// the pool is closed.
+func (p *connPool) evict(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.mutex.Lock()
+			idle := p.idle[:0]
+			for _, conn := range p.idle {
+				if p.expired(conn, now) {
+					conn.Close()
+				} else {
+					idle = append(idle, conn)
+				}
+			}
+			p.idle = idle
+			p.mutex.Unlock()
+		}
+	}
+}
+
+// close closes the idle connections. Connections still lent
+// out are closed as they are returned.
+func (p *connPool) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.done)
+	for _, conn := range p.idle {
+		conn.Close()
+	}
+	p.idle = nil
+}
+
+func (p *connPool) stats() PoolStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return PoolStats{
+		Capacity:     cap(p.slots),
+		InUse:        len(p.slots),
+		Idle:         len(p.idle),
+		Waiters:      p.waiters,
+		WaitCount:    p.waitCount,
+		WaitDuration: p.waitDuration,
+		Timeouts:     p.timeouts,
+	}
+}
@@ -0,0 +1,140 @@
+package goworker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// stubConn is a redis.Conn that only records being closed.
+type stubConn struct {
+	closed bool
+}
+
+func (c *stubConn) Close() error                                   { c.closed = true; return nil }
+func (c *stubConn) Err() error                                     { return nil }
+func (c *stubConn) Do(string, ...interface{}) (interface{}, error) { return nil, nil }
+func (c *stubConn) Send(string, ...interface{}) error              { return nil }
+func (c *stubConn) Flush() error                                   { return nil }
+func (c *stubConn) Receive() (interface{}, error)                  { return nil, nil }
+
+func newStubPool(capacity int, timeout, maxLifetime, idleTimeout time.Duration) (*connPool, *int) {
+	dials := 0
+	return newConnPool(func() (*RedisConn, error) {
+		dials++
+		return &RedisConn{Conn: &stubConn{}}, nil
+	}, capacity, timeout, maxLifetime, idleTimeout), &dials
+}
+
+func TestConnPoolReuse(t *testing.T) {
+	p, dials := newStubPool(2, 0, 0, 0)
+	defer p.close()
+
+	conn, err := p.get(context.Background())
+	if err != nil {
+		t.Fatalf("get: error %s", err)
+	}
+	p.put(conn)
+	again, err := p.get(context.Background())
+	if err != nil {
+		t.Fatalf("get: error %s", err)
+	}
+	if again != conn || *dials != 1 {
+		t.Errorf("get: expected the returned connection to be reused, dialed %d times", *dials)
+	}
+
+	p.discard(again)
+	if !again.Conn.(*stubConn).closed {
+		t.Errorf("discard: expected the connection to be closed")
+	}
+	if _, err := p.get(context.Background()); err != nil || *dials != 2 {
+		t.Errorf("get: expected a new connection after discard, dialed %d times, error %v", *dials, err)
+	}
+}
+
+func TestConnPoolWait(t *testing.T) {
+	p, _ := newStubPool(1, 20*time.Millisecond, 0, 0)
+	defer p.close()
+
+	conn, err := p.get(context.Background())
+	if err != nil {
+		t.Fatalf("get: error %s", err)
+	}
+
+	if _, err := p.get(context.Background()); err != errorPoolTimeout {
+		t.Errorf("get: expected %v, actual %v", errorPoolTimeout, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.get(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("get: expected %v, actual %v", context.Canceled, err)
+	}
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		p.put(conn)
+	}()
+	if _, err := p.get(context.Background()); err != nil {
+		t.Errorf("get: expected the returned connection, actual error %v", err)
+	}
+
+	stats := p.stats()
+	if stats.Capacity != 1 || stats.InUse != 1 || stats.Waiters != 0 || stats.WaitCount != 3 || stats.Timeouts != 2 || stats.WaitDuration <= 0 {
+		t.Errorf("stats: unexpected %+v", stats)
+	}
+}
+
+func TestConnPoolExpiry(t *testing.T) {
+	var expiryTests = []struct {
+		maxLifetime time.Duration
+		idleTimeout time.Duration
+		age         time.Duration
+		idle        time.Duration
+		reused      bool
+	}{
+		{0, 0, time.Hour, time.Hour, true},
+		{time.Minute, 0, time.Second, 0, true},
+		{time.Minute, 0, time.Hour, 0, false},
+		{0, time.Minute, 0, time.Second, true},
+		{0, time.Minute, 0, time.Hour, false},
+	}
+
+	for _, tt := range expiryTests {
+		p, dials := newStubPool(1, 0, tt.maxLifetime, tt.idleTimeout)
+		conn, err := p.get(context.Background())
+		if err != nil {
+			t.Fatalf("get: error %s", err)
+		}
+		p.put(conn)
+		conn.createdAt = time.Now().Add(-tt.age)
+		conn.lastUsed = time.Now().Add(-tt.idle)
+
+		if _, err := p.get(context.Background()); err != nil {
+			t.Fatalf("get: error %s", err)
+		}
+		if reused := *dials == 1; reused != tt.reused {
+			t.Errorf("get after %v open and %v idle with limits %v and %v: expected reuse %v, actual %v", tt.age, tt.idle, tt.maxLifetime, tt.idleTimeout, tt.reused, reused)
+		}
+		p.close()
+	}
+}
+
+func TestConnPoolEvict(t *testing.T) {
+	p, _ := newStubPool(1, 0, 0, 20*time.Millisecond)
+	defer p.close()
+
+	conn, err := p.get(context.Background())
+	if err != nil {
+		t.Fatalf("get: error %s", err)
+	}
+	conn.lastUsed = time.Now()
+	p.put(conn)
+
+	time.Sleep(60 * time.Millisecond)
+	if stats := p.stats(); stats.Idle != 0 {
+		t.Errorf("evict: expected no idle connections, actual %d", stats.Idle)
+	}
+}
@@ -0,0 +1,126 @@
+package goworker
+
+import (
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// priorityScoreLua defines priorityScore for the Lua scripts
+// that push jobs onto priority queues. The score of a job is
+// its priority plus a fraction that falls with each job
+// pushed onto the queue, counted in seqKey, so that jobs of
+// equal priority are popped in the order they were pushed.
+// The order wraps after 2^32 jobs.
+const priorityScoreLua = `
+local function priorityScore(seqKey, priority)
+	local seq = redis.call('INCR', seqKey) % 4294967295 + 1
+	return string.format('%.17g', tonumber(priority) + 1 - seq / 4294967296)
+end
+`
+
+// pushPrioritySource pushes the jobs in ARGV, given as pairs
+// of priority and payload, onto the priority queue KEYS[1]
+// whose sequence is counted in KEYS[2].
+const pushPrioritySource = priorityScoreLua + `
+for i = 1, #ARGV, 2 do
+	redis.call('ZADD', KEYS[1], priorityScore(KEYS[2], ARGV[i]), ARGV[i + 1])
+end
+return #ARGV / 2
+`
+
+// popPriorityScript removes and returns the job with the
+// highest priority from a priority queue, or nil if it is
+// empty.
+var popPriorityScript = redis.NewScript(1, `
+local jobs = redis.call('ZREVRANGE', KEYS[1], 0, 0)
+if jobs[1] then
+	redis.call('ZREM', KEYS[1], jobs[1])
+	return jobs[1]
+end
+return false
+`)
+
+// isPriorityQueue reports whether queue was listed in the
+// -priority-queues flag.
+func isPriorityQueue(queue string) bool {
+	for _, q := range workerSettings.PriorityQueues {
+		if q == queue {
+			return true
+		}
+	}
+	return false
+}
+
+// queueKey returns the Redis key holding the jobs of queue,
+// a list for ordinary and Sidekiq queues and a sorted set
+// for priority queues.
+func queueKey(queue string) string {
+	if isSidekiqQueue(queue) {
+		return sidekiqKey("queue:" + queue)
+	}
+	if isPriorityQueue(queue) {
+		return fmt.Sprintf("%spqueue:%s", workerSettings.Namespace, queue)
+	}
+	return fmt.Sprintf("%squeue:%s", workerSettings.Namespace, queue)
+}
+
+// prioritySeqKey returns the Redis key counting the jobs
+// pushed onto the priority queue, in the same Redis Cluster
+// slot as the queue.
+func prioritySeqKey(queue string) string {
+	key := queueKey(queue)
+	if clusterSlot(key+":seq") == clusterSlot(key) {
+		return key + ":seq"
+	}
+	return "{" + key + "}:seq"
+}
+
+// queuesKey returns the Redis set listing the queue names
+// known alongside queue.
+func queuesKey(queue string) string {
+	if isSidekiqQueue(queue) {
+		return sidekiqKey("queues")
+	}
+	return fmt.Sprintf("%squeues", workerSettings.Namespace)
+}
+
+// pushCommand returns the command and arguments that add
+// the job encoded in buffer to the tail of queue, or for a
+// priority queue, behind the jobs of the same priority.
+// Sidekiq pops jobs from the right, so its queues are pushed
+// on the left.
+func pushCommand(queue string, payload *Payload, buffer []byte) (string, []interface{}) {
+	if isSidekiqQueue(queue) {
+		return "LPUSH", []interface{}{queueKey(queue), buffer}
+	}
+	if isPriorityQueue(queue) {
+		return "EVAL", []interface{}{pushPrioritySource, 2, queueKey(queue), prioritySeqKey(queue), payload.Priority, buffer}
+	}
+	return "RPUSH", []interface{}{queueKey(queue), buffer}
+}
+
+// requeueCommand is like pushCommand, but puts a job back at
+// the head of an ordinary queue so that it is the next one
+// popped.
+func requeueCommand(queue string, payload *Payload, buffer []byte) (string, []interface{}) {
+	if isSidekiqQueue(queue) {
+		return "RPUSH", []interface{}{queueKey(queue), buffer}
+	}
+	if isPriorityQueue(queue) {
+		return pushCommand(queue, payload, buffer)
+	}
+	return "LPUSH", []interface{}{queueKey(queue), buffer}
+}
+
+// popJob removes the next job from queue, returning nil if
+// the queue is empty.
+func popJob(conn *RedisConn, queue string) (interface{}, error) {
+	if isSidekiqQueue(queue) {
+		return conn.Do("RPOP", queueKey(queue))
+	}
+	if isPriorityQueue(queue) {
+		return conn.runScript(popPriorityScript, queueKey(queue))
+	}
+	return conn.Do("LPOP", queueKey(queue))
+}
@@ -0,0 +1,102 @@
+package goworker
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	workerSettings.Queues = []string{"prio"}
+	workerSettings.PriorityQueues = []string{"prio"}
+	defer func() { workerSettings.PriorityQueues = nil }()
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+	conn.Do("DEL", queueKey("prio"))
+
+	for _, args := range []int{1, 11} {
+		if err := Enqueue(&Job{Queue: "prio", Payload: Payload{Class: "Prioritized", Args: []interface{}{args}, Priority: 1}}); err != nil {
+			t.Fatalf("Enqueue: error %s", err)
+		}
+	}
+	for _, err := range EnqueueBatch([]*Job{
+		{Queue: "prio", Payload: Payload{Class: "Prioritized", Args: []interface{}{5}, Priority: 5}},
+		{Queue: "prio", Payload: Payload{Class: "Prioritized", Args: []interface{}{3}, Priority: 3}},
+		{Queue: "prio", Payload: Payload{Class: "Prioritized", Args: []interface{}{13}, Priority: 3}},
+		{Queue: "prio", Payload: Payload{Class: "Prioritized", Args: []interface{}{-1}, Priority: -1}},
+	}) {
+		if err != nil {
+			t.Fatalf("EnqueueBatch: error %s", err)
+		}
+	}
+	if err := Enqueue(&Job{Queue: "prio", Payload: Payload{Class: "Prioritized", Args: []interface{}{23}, Priority: 3}}); err != nil {
+		t.Fatalf("Enqueue: error %s", err)
+	}
+
+	// Jobs of equal priority come out in the order they were
+	// pushed, whether by Enqueue or EnqueueBatch.
+	for _, expected := range []float64{5, 3, 13, 23, 1, 11, -1} {
+		reply, err := popJob(conn, "prio")
+		if err != nil {
+			t.Fatalf("popJob: error %s", err)
+		}
+		buffer, ok := reply.([]byte)
+		if !ok {
+			t.Fatalf("popJob: expected the job with args [%v], actual %v", expected, reply)
+		}
+		var payload Payload
+		if err := json.Unmarshal(buffer, &payload); err != nil {
+			t.Fatalf("popJob: error %s", err)
+		}
+		if len(payload.Args) != 1 || payload.Args[0] != expected {
+			t.Errorf("popJob: expected the job with args [%v], actual %v", expected, payload.Args)
+		}
+	}
+
+	reply, err := popJob(conn, "prio")
+	if err != nil || reply != nil {
+		t.Errorf("popJob: expected nil from an empty queue, actual %v (err %v)", reply, err)
+	}
+}
+
+var pushCommandTests = []struct {
+	queue    string
+	requeue  bool
+	expected string
+}{
+	{"plain", false, "RPUSH resque:queue:plain job"},
+	{"plain", true, "LPUSH resque:queue:plain job"},
+	{"ranked", false, "EVAL " + pushPrioritySource + " 2 resque:pqueue:ranked {resque:pqueue:ranked}:seq 7 job"},
+	{"ranked", true, "EVAL " + pushPrioritySource + " 2 resque:pqueue:ranked {resque:pqueue:ranked}:seq 7 job"},
+}
+
+func TestPushCommand(t *testing.T) {
+	workerSettings.PriorityQueues = []string{"ranked"}
+	defer func() { workerSettings.PriorityQueues = nil }()
+
+	payload := &Payload{Priority: 7}
+	for _, tt := range pushCommandTests {
+		command, args := pushCommand(tt.queue, payload, []byte("job"))
+		if tt.requeue {
+			command, args = requeueCommand(tt.queue, payload, []byte("job"))
+		}
+		actual := command
+		for _, arg := range args {
+			if buffer, ok := arg.([]byte); ok {
+				arg = string(buffer)
+			}
+			actual += fmt.Sprintf(" %v", arg)
+		}
+		if actual != tt.expected {
+			t.Errorf("pushCommand(%s, %v): expected %s, actual %s", tt.queue, tt.requeue, tt.expected, actual)
+		}
+	}
+}
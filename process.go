@@ -2,7 +2,6 @@ package goworker
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"strings"
 	"time"
@@ -74,17 +73,3 @@ func (p *process) fail(conn *RedisConn) error {
 
 	return nil
 }
-
-func (p *process) queues(strict bool) []string {
-	// If the queues order is strict then just return them.
-	if strict {
-		return p.Queues
-	}
-
-	// If not then we want to to shuffle the queues before returning them.
-	queues := make([]string, len(p.Queues))
-	for i, v := range rand.Perm(len(p.Queues)) {
-		queues[i] = p.Queues[v]
-	}
-	return queues
-}
@@ -0,0 +1,93 @@
+package goworker
+
+import (
+	"math/rand"
+)
+
+// QueueSelector decides the order in which the poller checks
+// queues for a job. Select is called once per polling cycle
+// and returns the queues to check; the poller takes a job
+// from the first one that is not empty. Set a custom
+// selector in WorkerSettings.QueueSelector.
+type QueueSelector interface {
+	Select() []string
+}
+
+type strictQueueSelector struct {
+	queues []string
+}
+
+// NewStrictQueueSelector returns a selector that always
+// checks queues in the given order, so that a queue is
+// worked only when every queue before it is empty. This is
+// the selector used when -queues has no weights.
+func NewStrictQueueSelector(queues []string) QueueSelector {
+	return &strictQueueSelector{queues: queues}
+}
+
+func (s *strictQueueSelector) Select() []string {
+	return s.queues
+}
+
+type weightedQueueSelector struct {
+	queues  []string
+	weights []int
+	order   []string
+}
+
+// NewWeightedQueueSelector returns a selector that orders
+// queues by lottery on every cycle: each queue is checked
+// first with a probability proportional to its weight, and
+// so on for the remaining places. Queues missing from
+// weights have a weight of 1. This is the selector used
+// when -queues has weights, such as high=2,low=1.
+func NewWeightedQueueSelector(queues []string, weights map[string]int) QueueSelector {
+	s := &weightedQueueSelector{
+		queues:  queues,
+		weights: make([]int, len(queues)),
+		order:   make([]string, len(queues)),
+	}
+	for i, queue := range queues {
+		s.weights[i] = 1
+		if weight, ok := weights[queue]; ok && weight > 0 {
+			s.weights[i] = weight
+		}
+	}
+	return s
+}
+
+func (s *weightedQueueSelector) Select() []string {
+	remaining := make([]int, len(s.weights))
+	copy(remaining, s.weights)
+
+	total := 0
+	for _, weight := range remaining {
+		total += weight
+	}
+
+	for place := range s.order {
+		ticket := rand.Intn(total)
+		for i, weight := range remaining {
+			if ticket < weight {
+				s.order[place] = s.queues[i]
+				total -= weight
+				remaining[i] = 0
+				break
+			}
+			ticket -= weight
+		}
+	}
+	return s.order
+}
+
+// newQueueSelector returns the selector configured in
+// settings, or the default one for its queues and weights.
+func newQueueSelector(settings *WorkerSettings) QueueSelector {
+	if settings.QueueSelector != nil {
+		return settings.QueueSelector
+	}
+	if settings.IsStrict {
+		return NewStrictQueueSelector(settings.Queues)
+	}
+	return NewWeightedQueueSelector(settings.Queues, settings.QueueWeights)
+}
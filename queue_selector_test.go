@@ -0,0 +1,36 @@
+package goworker
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestStrictQueueSelector(t *testing.T) {
+	s := NewStrictQueueSelector([]string{"high", "low"})
+	for i := 0; i < 10; i++ {
+		if actual := fmt.Sprint(s.Select()); actual != "[high low]" {
+			t.Errorf("StrictQueueSelector: expected [high low], actual %s", actual)
+		}
+	}
+}
+
+func TestWeightedQueueSelector(t *testing.T) {
+	s := NewWeightedQueueSelector([]string{"high", "low", "other"}, map[string]int{"high": 1000, "low": 1})
+
+	first := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		order := s.Select()
+		first[order[0]]++
+
+		sorted := append([]string(nil), order...)
+		sort.Strings(sorted)
+		if actual := fmt.Sprint(sorted); actual != "[high low other]" {
+			t.Fatalf("WeightedQueueSelector: expected each queue once, actual %v", order)
+		}
+	}
+
+	if first["high"] < 950 {
+		t.Errorf("WeightedQueueSelector: expected high first in nearly every cycle, actual %d of 1000", first["high"])
+	}
+}
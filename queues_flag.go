@@ -14,18 +14,15 @@ var (
 
 type queuesFlag []string
 
+// Set adds each distinct queue named in value once. Weights
+// are read separately by parseQueues.
 func (q *queuesFlag) Set(value string) error {
-	for _, queueAndWeight := range strings.Split(value, ",") {
-		if queueAndWeight == "" {
-			continue
-		}
-
-		queue, weight, err := parseQueueAndWeight(queueAndWeight)
-		if err != nil {
-			return err
-		}
-
-		for i := 0; i < weight; i++ {
+	queues, _, err := parseQueues(value)
+	if err != nil {
+		return err
+	}
+	for _, queue := range queues {
+		if !q.contains(queue) {
 			*q = append(*q, queue)
 		}
 	}
@@ -39,6 +36,43 @@ func (q *queuesFlag) String() string {
 	return fmt.Sprint(*q)
 }
 
+func (q *queuesFlag) contains(queue string) bool {
+	for _, v := range *q {
+		if v == queue {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQueues parses a comma-separated list of queues with
+// optional weights, such as high=2,low. Queues are returned
+// in the order they are first named, and a queue named more
+// than once gets the sum of its weights. Queues with a
+// weight of zero or less are left out.
+func parseQueues(value string) (queues []string, weights map[string]int, err error) {
+	weights = make(map[string]int)
+	for _, queueAndWeight := range strings.Split(value, ",") {
+		if queueAndWeight == "" {
+			continue
+		}
+
+		queue, weight, err := parseQueueAndWeight(queueAndWeight)
+		if err != nil {
+			return nil, nil, err
+		}
+		if queue == "" || weight <= 0 {
+			continue
+		}
+
+		if _, ok := weights[queue]; !ok {
+			queues = append(queues, queue)
+		}
+		weights[queue] += weight
+	}
+	return queues, weights, nil
+}
+
 func parseQueueAndWeight(queueAndWeight string) (queue string, weight int, err error) {
 	parts := strings.SplitN(queueAndWeight, "=", 2)
 	queue = parts[0]
@@ -28,17 +28,22 @@ var queuesFlagSetTests = []struct {
 	},
 	{
 		"high=2,low=1",
-		queuesFlag([]string{"high", "high", "low"}),
+		queuesFlag([]string{"high", "low"}),
 		nil,
 	},
 	{
 		"high=2,low",
-		queuesFlag([]string{"high", "high", "low"}),
+		queuesFlag([]string{"high", "low"}),
 		nil,
 	},
 	{
 		"low=1,high=2",
-		queuesFlag([]string{"low", "high", "high"}),
+		queuesFlag([]string{"low", "high"}),
+		nil,
+	},
+	{
+		"high=1000,low,high",
+		queuesFlag([]string{"high", "low"}),
 		nil,
 	},
 	{
@@ -63,7 +68,7 @@ var queuesFlagSetTests = []struct {
 	},
 	{
 		"high=2,,,=1",
-		queuesFlag([]string{"high", "high"}),
+		queuesFlag([]string{"high"}),
 		nil,
 	},
 	{
@@ -115,3 +120,42 @@ func TestQueuesFlagString(t *testing.T) {
 		}
 	}
 }
+
+var parseQueuesTests = []struct {
+	v       string
+	queues  []string
+	weights map[string]int
+}{
+	{
+		"high",
+		[]string{"high"},
+		map[string]int{"high": 1},
+	},
+	{
+		"high=2,low=1",
+		[]string{"high", "low"},
+		map[string]int{"high": 2, "low": 1},
+	},
+	{
+		"high=1000,low,high",
+		[]string{"high", "low"},
+		map[string]int{"high": 1001, "low": 1},
+	},
+	{
+		"off=0,on",
+		[]string{"on"},
+		map[string]int{"on": 1},
+	},
+}
+
+func TestParseQueues(t *testing.T) {
+	for _, tt := range parseQueuesTests {
+		queues, weights, err := parseQueues(tt.v)
+		if err != nil {
+			t.Errorf("parseQueues(%s): error %s", tt.v, err)
+		}
+		if fmt.Sprint(queues) != fmt.Sprint(tt.queues) || fmt.Sprint(weights) != fmt.Sprint(tt.weights) {
+			t.Errorf("parseQueues(%s): expected %v %v, actual %v %v", tt.v, tt.queues, tt.weights, queues, weights)
+		}
+	}
+}
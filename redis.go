@@ -7,10 +7,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
-	"vitess.io/vitess/go/pools"
 )
 
 var (
@@ -19,20 +19,97 @@ var (
 
 type RedisConn struct {
 	redis.Conn
+	readOnly  bool
+	pool      *connPool
+	createdAt time.Time
+	lastUsed  time.Time
 }
 
 func (r *RedisConn) Close() {
 	_ = r.Conn.Close()
 }
 
-func newRedisFactory(uri string) pools.Factory {
-	return func() (pools.Resource, error) {
-		return redisConnFromURI(uri)
+// Do sends a command and returns its reply, noting whether
+// the server answered as a read-only replica. Flushing with
+// an empty command returns the pending replies without an
+// error, so those are checked one by one.
+func (r *RedisConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	reply, err := r.Conn.Do(commandName, args...)
+	if isReadOnlyError(err) {
+		r.readOnly = true
+	}
+	if replies, ok := reply.([]interface{}); ok && commandName == "" {
+		for _, reply := range replies {
+			if err, ok := reply.(redis.Error); ok && isReadOnlyError(err) {
+				r.readOnly = true
+			}
+		}
+	}
+	return reply, err
+}
+
+// Receive reads a reply, noting whether the server answered
+// as a read-only replica.
+func (r *RedisConn) Receive() (interface{}, error) {
+	reply, err := r.Conn.Receive()
+	if isReadOnlyError(err) {
+		r.readOnly = true
+	}
+	return reply, err
+}
+
+// runScript runs script, noting like Do whether the server
+// answered as a read-only replica.
+func (r *RedisConn) runScript(script *redis.Script, keysAndArgs ...interface{}) (interface{}, error) {
+	reply, err := script.Do(r.Conn, keysAndArgs...)
+	if isReadOnlyError(err) {
+		r.readOnly = true
+	}
+	return reply, err
+}
+
+// flush sends the commands queued with Send and waits for
+// their replies, returning them with the first error among
+// them.
+func (r *RedisConn) flush() ([]interface{}, error) {
+	reply, err := r.Do("")
+	if err != nil {
+		return nil, err
+	}
+	replies, _ := reply.([]interface{})
+	for _, reply := range replies {
+		if err, ok := reply.(redis.Error); ok {
+			return replies, err
+		}
 	}
+	return replies, nil
 }
 
-func newRedisPool(uri string, capacity int, maxCapacity int, idleTimout time.Duration) *pools.ResourcePool {
-	return pools.NewResourcePool(newRedisFactory(uri), capacity, maxCapacity, idleTimout)
+// healthy reports whether the connection still answers. A
+// connection that was used recently is assumed to, and one
+// that sat idle is checked with PING.
+func (r *RedisConn) healthy() bool {
+	if r.broken() {
+		return false
+	}
+	if r.lastUsed.IsZero() || time.Since(r.lastUsed) < pingAfterIdle {
+		return true
+	}
+	_, err := r.Do("PING")
+	return err == nil
+}
+
+// broken reports whether the connection can no longer be
+// used, either because it failed or because its server is
+// now a replica.
+func (r *RedisConn) broken() bool {
+	return r.readOnly || r.Conn.Err() != nil
+}
+
+func newRedisPool(uri string, capacity int, timeout, maxLifetime, idleTimeout time.Duration) *connPool {
+	return newConnPool(func() (*RedisConn, error) {
+		return redisConnFromURI(uri)
+	}, capacity, timeout, maxLifetime, idleTimeout)
 }
 
 func redisConnFromURI(uriString string) (*RedisConn, error) {
@@ -43,34 +120,41 @@ func redisConnFromURI(uriString string) (*RedisConn, error) {
 
 	var network string
 	var host string
-	var password string
 	var db string
 	var dialOptions []redis.DialOption
 
+	username, password := credentials(uri)
+
 	switch uri.Scheme {
 	case "redis", "rediss":
 		network = "tcp"
 		host = uri.Host
-		if uri.User != nil {
-			password, _ = uri.User.Password()
-		}
 		if len(uri.Path) > 1 {
 			db = uri.Path[1:]
 		}
 		if uri.Scheme == "rediss" {
+			config, err := tlsConfig()
+			if err != nil {
+				return nil, err
+			}
 			dialOptions = append(dialOptions, redis.DialUseTLS(true))
 			dialOptions = append(dialOptions, redis.DialTLSSkipVerify(workerSettings.SkipTLSVerify))
-			if len(workerSettings.TLSCertPath) > 0 {
-				pool, err := getCertPool(workerSettings.TLSCertPath)
-				if err != nil {
-					return nil, err
-				}
-				config := &tls.Config{
-					RootCAs: pool,
-				}
-				dialOptions = append(dialOptions, redis.DialTLSConfig(config))
-			}
+			dialOptions = append(dialOptions, redis.DialTLSConfig(config))
+		}
+	case "redis-sentinel":
+		network = "tcp"
+		var name string
+		name, db = parseSentinelPath(uri.Path)
+		host, err = sentinelMasterAddr(strings.Split(uri.Host, ","), name, uri.Query().Get("sentinel_password"))
+		if err != nil {
+			return nil, err
 		}
+	case "redis-cluster":
+		conn, err := newClusterConn(strings.Split(uri.Host, ","), []redis.DialOption{redis.DialUsername(username), redis.DialPassword(password)})
+		if err != nil {
+			return nil, err
+		}
+		return &RedisConn{Conn: conn}, nil
 	case "unix":
 		network = "unix"
 		host = uri.Path
@@ -84,8 +168,7 @@ func redisConnFromURI(uriString string) (*RedisConn, error) {
 	}
 
 	if password != "" {
-		_, err := conn.Do("AUTH", password)
-		if err != nil {
+		if err := authenticate(conn, username, password); err != nil {
 			conn.Close()
 			return nil, err
 		}
@@ -102,17 +185,103 @@ func redisConnFromURI(uriString string) (*RedisConn, error) {
 	return &RedisConn{Conn: conn}, nil
 }
 
+// credentials returns the username and password in uri, or
+// else those of the -username and -password flags.
+func credentials(uri *url.URL) (username string, password string) {
+	if uri.User != nil {
+		username = uri.User.Username()
+		password, _ = uri.User.Password()
+	}
+	if username == "" && password == "" {
+		return workerSettings.Username, workerSettings.Password
+	}
+	return username, password
+}
+
+// authenticate sends AUTH with the username, as Redis 6 ACLs
+// expect. Servers before Redis 6 reject a username as the
+// wrong number of arguments, and URIs often carry a
+// placeholder one, so then the password is sent alone. Any
+// other error, such as a wrong password, is returned.
+func authenticate(conn redis.Conn, username string, password string) error {
+	if username != "" {
+		_, err := conn.Do("AUTH", username, password)
+		if !isAuthArityError(err) {
+			return err
+		}
+	}
+	_, err := conn.Do("AUTH", password)
+	return err
+}
+
+// isAuthArityError reports whether err is the error servers
+// before Redis 6 return for AUTH with a username.
+func isAuthArityError(err error) bool {
+	redisErr, ok := err.(redis.Error)
+	return ok && strings.Contains(strings.ToLower(string(redisErr)), "wrong number of arguments for 'auth'")
+}
+
+// tlsConfig builds the TLS configuration for rediss
+// connections from the TLS settings.
+func tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: workerSettings.SkipTLSVerify,
+		ServerName:         workerSettings.TLSServerName,
+	}
+
+	if len(workerSettings.TLSCertPath) > 0 {
+		pool, err := getCertPool(workerSettings.TLSCertPath)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if len(workerSettings.TLSClientCertPath) > 0 || len(workerSettings.TLSClientKeyPath) > 0 {
+		cert, err := tls.LoadX509KeyPair(workerSettings.TLSClientCertPath, workerSettings.TLSClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load the TLS client certificate %q and key %q: %v", workerSettings.TLSClientCertPath, workerSettings.TLSClientKeyPath, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(workerSettings.TLSMinVersion) > 0 {
+		version, err := parseTLSVersion(workerSettings.TLSMinVersion)
+		if err != nil {
+			return nil, err
+		}
+		config.MinVersion = version
+	}
+
+	return config, nil
+}
+
+// parseTLSVersion parses a TLS version such as 1.2.
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("Unknown TLS version %q, expected one of 1.0, 1.1, 1.2 or 1.3", version)
+}
+
 func getCertPool(certPath string) (*x509.CertPool, error) {
 	rootCAs, _ := x509.SystemCertPool()
 	if rootCAs == nil {
 		rootCAs = x509.NewCertPool()
 	}
-	certs, err := ioutil.ReadFile(workerSettings.TLSCertPath)
+	certs, err := ioutil.ReadFile(certPath)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to read %q for the RootCA pool: %v", workerSettings.TLSCertPath, err)
+		return nil, fmt.Errorf("Failed to read %q for the RootCA pool: %v", certPath, err)
 	}
 	if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
-		return nil, fmt.Errorf("Failed to append %q to the RootCA pool: %v", workerSettings.TLSCertPath, err)
+		return nil, fmt.Errorf("Failed to append %q to the RootCA pool: %v", certPath, err)
 	}
 	return rootCAs, nil
 }
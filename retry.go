@@ -0,0 +1,69 @@
+package goworker
+
+import (
+	"time"
+)
+
+const (
+	// minRetryBackoff and maxRetryBackoff bound the wait
+	// between attempts to reach Redis.
+	minRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff = 10 * time.Second
+
+	// connRetryAttempts is how many times a worker tries to
+	// get a connection before giving up on an operation.
+	connRetryAttempts = 10
+
+	// pingAfterIdle is how long a pooled connection may sit
+	// unused before it is checked with PING when borrowed.
+	pingAfterIdle = 10 * time.Second
+)
+
+// backoff computes exponentially growing waits between
+// retries.
+type backoff struct {
+	attempt int
+}
+
+// next returns the wait before the next retry, doubling from
+// minRetryBackoff up to maxRetryBackoff.
+func (b *backoff) next() time.Duration {
+	wait := minRetryBackoff << uint(b.attempt)
+	if wait > maxRetryBackoff || wait <= 0 {
+		wait = maxRetryBackoff
+	} else {
+		b.attempt++
+	}
+	return wait
+}
+
+// reset starts the waits over after a success.
+func (b *backoff) reset() {
+	b.attempt = 0
+}
+
+// sleep waits for d, returning false if quit is closed
+// first.
+func sleep(d time.Duration, quit <-chan bool) bool {
+	select {
+	case <-quit:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// getConnRetrying gets a connection like GetConn, retrying
+// with backoff while Redis cannot be reached.
+func getConnRetrying() (*RedisConn, error) {
+	var retry backoff
+	for attempt := 1; ; attempt++ {
+		conn, err := GetConn()
+		if err == nil || attempt == connRetryAttempts {
+			return conn, err
+		}
+		wait := retry.next()
+		logger.Errorf("Error on getting connection, retrying in %v: %v", wait, err)
+		time.Sleep(wait)
+	}
+}
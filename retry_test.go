@@ -0,0 +1,83 @@
+package goworker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	var retry backoff
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		1600 * time.Millisecond,
+		3200 * time.Millisecond,
+		6400 * time.Millisecond,
+		10 * time.Second,
+		10 * time.Second,
+	}
+	for i, wait := range expected {
+		if actual := retry.next(); actual != wait {
+			t.Errorf("backoff.next() %d: expected %v, actual %v", i, wait, actual)
+		}
+	}
+
+	retry.reset()
+	if actual := retry.next(); actual != minRetryBackoff {
+		t.Errorf("backoff.next() after reset: expected %v, actual %v", minRetryBackoff, actual)
+	}
+}
+
+func TestRedisConnHealthy(t *testing.T) {
+	pings := make(chan struct{}, 10)
+	addr := serveFakeRedis(t, func(args []string) string {
+		if strings.ToUpper(args[0]) == "PING" {
+			pings <- struct{}{}
+		}
+		return "+PONG\r\n"
+	})
+
+	var healthTests = []struct {
+		idle    time.Duration
+		closed  bool
+		healthy bool
+		pinged  bool
+	}{
+		{0, false, true, false},
+		{time.Second, false, true, false},
+		{time.Minute, false, true, true},
+		{0, true, false, false},
+	}
+
+	for _, tt := range healthTests {
+		conn, err := redisConnFromURI(fmt.Sprintf("redis://%s/", addr))
+		if err != nil {
+			t.Fatalf("redisConnFromURI: error %s", err)
+		}
+		if tt.idle > 0 {
+			conn.lastUsed = time.Now().Add(-tt.idle)
+		}
+		if tt.closed {
+			conn.Close()
+		}
+
+		if healthy := conn.healthy(); healthy != tt.healthy {
+			t.Errorf("healthy() after %v idle, closed %v: expected %v, actual %v", tt.idle, tt.closed, tt.healthy, healthy)
+		}
+		select {
+		case <-pings:
+			if !tt.pinged {
+				t.Errorf("healthy() after %v idle: expected no PING", tt.idle)
+			}
+		default:
+			if tt.pinged {
+				t.Errorf("healthy() after %v idle: expected a PING", tt.idle)
+			}
+		}
+		conn.Close()
+	}
+}
@@ -0,0 +1,90 @@
+package goworker
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	sentinelTimeout     = 2 * time.Second
+	readOnlyErrorPrefix = "READONLY "
+)
+
+var (
+	errorNoSentinels    = errors.New("no sentinels in Redis Sentinel URI")
+	errorNoMasterName   = errors.New("no master name in Redis Sentinel URI")
+	errorMasterNotFound = errors.New("no sentinel knows the master")
+)
+
+// parseSentinelPath splits the path of a redis-sentinel URI,
+// such as /mymaster/2, into the master name and database.
+func parseSentinelPath(path string) (name string, db string) {
+	parts := strings.SplitN(strings.Trim(path, "/"), "/", 2)
+	name = parts[0]
+	if len(parts) == 2 {
+		db = parts[1]
+	}
+	return
+}
+
+// sentinelMasterAddr asks each sentinel in turn for the
+// address of the master called name, and returns the first
+// answer. Sentinels that cannot be reached or do not know
+// the master are skipped, and the last error is returned
+// if none answers.
+func sentinelMasterAddr(sentinels []string, name string, password string) (string, error) {
+	if len(sentinels) == 0 || sentinels[0] == "" {
+		return "", errorNoSentinels
+	}
+	if name == "" {
+		return "", errorNoMasterName
+	}
+
+	lastErr := errorMasterNotFound
+	for _, sentinel := range sentinels {
+		addr, err := querySentinel(sentinel, name, password)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return addr, nil
+	}
+	return "", lastErr
+}
+
+func querySentinel(sentinel string, name string, password string) (string, error) {
+	conn, err := redis.Dial("tcp", sentinel,
+		redis.DialConnectTimeout(sentinelTimeout),
+		redis.DialReadTimeout(sentinelTimeout),
+		redis.DialWriteTimeout(sentinelTimeout),
+		redis.DialPassword(password),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	reply, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", name))
+	if err == redis.ErrNil {
+		return "", errorMasterNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(reply) != 2 {
+		return "", errorMasterNotFound
+	}
+	return net.JoinHostPort(reply[0], reply[1]), nil
+}
+
+// isReadOnlyError reports whether err is the reply of a
+// replica to a write. After a failover the old master
+// becomes a replica, so connections to it must be replaced.
+func isReadOnlyError(err error) bool {
+	var redisErr redis.Error
+	return errors.As(err, &redisErr) && strings.HasPrefix(string(redisErr), readOnlyErrorPrefix)
+}
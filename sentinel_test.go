@@ -0,0 +1,170 @@
+package goworker
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// serveFakeRedis answers each command sent to the returned
+// address with the raw RESP reply chosen by respond.
+func serveFakeRedis(t *testing.T, respond func(args []string) string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: error %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					args, err := readFakeCommand(reader)
+					if err != nil {
+						return
+					}
+					fmt.Fprint(conn, respond(args))
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func readFakeCommand(reader *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(reader, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		var size int
+		if _, err := fmt.Fscanf(reader, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+		buffer := make([]byte, size+2)
+		if _, err := reader.Read(buffer); err != nil {
+			return nil, err
+		}
+		args[i] = string(buffer[:size])
+	}
+	return args, nil
+}
+
+func TestSentinelMasterAddr(t *testing.T) {
+	known := serveFakeRedis(t, func(args []string) string {
+		if strings.ToUpper(args[0]) == "SENTINEL" && args[2] == "mymaster" {
+			return "*2\r\n$9\r\n127.0.0.1\r\n$4\r\n6379\r\n"
+		}
+		return "*-1\r\n"
+	})
+	unknown := serveFakeRedis(t, func(args []string) string {
+		return "*-1\r\n"
+	})
+
+	var sentinelTests = []struct {
+		sentinels []string
+		name      string
+		addr      string
+		err       error
+	}{
+		{[]string{known}, "mymaster", "127.0.0.1:6379", nil},
+		{[]string{unknown, known}, "mymaster", "127.0.0.1:6379", nil},
+		{[]string{unknown}, "mymaster", "", errorMasterNotFound},
+		{[]string{known}, "other", "", errorMasterNotFound},
+		{[]string{known}, "", "", errorNoMasterName},
+		{[]string{""}, "mymaster", "", errorNoSentinels},
+	}
+
+	for _, tt := range sentinelTests {
+		addr, err := sentinelMasterAddr(tt.sentinels, tt.name, "")
+		if addr != tt.addr || err != tt.err {
+			t.Errorf("sentinelMasterAddr(%v, %s): expected %s %v, actual %s %v", tt.sentinels, tt.name, tt.addr, tt.err, addr, err)
+		}
+	}
+}
+
+func TestParseSentinelPath(t *testing.T) {
+	var pathTests = []struct {
+		path string
+		name string
+		db   string
+	}{
+		{"/mymaster", "mymaster", ""},
+		{"/mymaster/2", "mymaster", "2"},
+		{"/mymaster/", "mymaster", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range pathTests {
+		name, db := parseSentinelPath(tt.path)
+		if name != tt.name || db != tt.db {
+			t.Errorf("parseSentinelPath(%q): expected %q %q, actual %q %q", tt.path, tt.name, tt.db, name, db)
+		}
+	}
+}
+
+func TestRedisConnFromSentinelURI(t *testing.T) {
+	replica := serveFakeRedis(t, func(args []string) string {
+		return "-READONLY You can't write against a read only replica.\r\n"
+	})
+	host, port, _ := net.SplitHostPort(replica)
+	sentinel := serveFakeRedis(t, func(args []string) string {
+		return fmt.Sprintf("*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(host), host, len(port), port)
+	})
+
+	unknown := serveFakeRedis(t, func(args []string) string {
+		return "*-1\r\n"
+	})
+
+	conn, err := redisConnFromURI(fmt.Sprintf("redis-sentinel://%s,%s/mymaster", unknown, sentinel))
+	if err != nil {
+		t.Fatalf("redisConnFromURI: error %s", err)
+	}
+	defer conn.Close()
+
+	if conn.broken() {
+		t.Errorf("redisConnFromURI: expected a usable connection")
+	}
+	if _, err := conn.Do("SET", "key", "value"); !isReadOnlyError(err) {
+		t.Errorf("Do: expected a READONLY error, actual %v", err)
+	}
+	if !conn.broken() {
+		t.Errorf("Do: expected a connection to a replica to be broken")
+	}
+
+	pipelined, err := redisConnFromURI(fmt.Sprintf("redis-sentinel://%s/mymaster", sentinel))
+	if err != nil {
+		t.Fatalf("redisConnFromURI: error %s", err)
+	}
+	defer pipelined.Close()
+
+	pipelined.Send("SET", "key", "value")
+	if _, err := pipelined.Do(""); err != nil {
+		t.Errorf("Do: error %s", err)
+	}
+	if !pipelined.broken() {
+		t.Errorf("Do: expected a pipelined write to a replica to break the connection")
+	}
+
+	scripted, err := redisConnFromURI(fmt.Sprintf("redis-sentinel://%s/mymaster", sentinel))
+	if err != nil {
+		t.Fatalf("redisConnFromURI: error %s", err)
+	}
+	defer scripted.Close()
+
+	if _, err := scripted.runScript(popPriorityScript, "key"); !isReadOnlyError(err) {
+		t.Errorf("runScript: expected a READONLY error, actual %v", err)
+	}
+	if !scripted.broken() {
+		t.Errorf("runScript: expected a script run against a replica to break the connection")
+	}
+}
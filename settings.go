@@ -0,0 +1,187 @@
+package goworker
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FieldError describes one invalid setting.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// ValidationError lists every invalid setting found by
+// Validate.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return "invalid goworker settings: " + strings.Join(messages, "; ")
+}
+
+// normalize fills in the settings derived from others, such
+// as Queues from QueuesString and Interval from
+// IntervalFloat, whether they came from flags or
+// SetSettings.
+func (s *WorkerSettings) normalize() error {
+	if s.QueuesString != "" {
+		queues, weights, err := parseQueues(s.QueuesString)
+		if err != nil {
+			return err
+		}
+		s.Queues = queuesFlag(queues)
+		s.QueueWeights = weights
+		s.IsStrict = strings.IndexRune(s.QueuesString, '=') == -1
+	} else {
+		s.IsStrict = len(s.QueueWeights) == 0
+	}
+
+	if s.PriorityQueuesString != "" {
+		s.PriorityQueues = splitQueueList(s.PriorityQueuesString)
+	}
+	if s.SidekiqQueuesString != "" {
+		s.SidekiqQueues = splitQueueList(s.SidekiqQueuesString)
+	}
+
+	if s.IntervalFloat != 0 {
+		if err := s.Interval.SetFloat(s.IntervalFloat); err != nil {
+			return err
+		}
+	}
+
+	if s.HashTag {
+		s.Namespace = hashTagNamespace(s.Namespace)
+	}
+	return nil
+}
+
+// splitQueueList splits a comma-separated list of queues,
+// skipping empty names.
+func splitQueueList(value string) []string {
+	var queues []string
+	for _, queue := range strings.Split(value, ",") {
+		if queue != "" {
+			queues = append(queues, queue)
+		}
+	}
+	return queues
+}
+
+// Validate checks the settings before anything connects to
+// Redis, returning a *ValidationError that lists every
+// invalid field.
+func (s WorkerSettings) Validate() error {
+	var errs []*FieldError
+	invalid := func(field string, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Concurrency < 1 {
+		invalid("Concurrency", "must be at least 1, not %d", s.Concurrency)
+	}
+	if s.Connections < 1 {
+		invalid("Connections", "must be at least 1, not %d", s.Connections)
+	}
+	if s.Interval <= 0 {
+		invalid("Interval", "must be positive, not %v", time.Duration(s.Interval))
+	}
+	if s.Namespace == "" {
+		invalid("Namespace", "must not be empty")
+	}
+	if s.StatusTTL < 0 {
+		invalid("StatusTTL", "must not be negative")
+	}
+	if s.PoisonThreshold < 0 {
+		invalid("PoisonThreshold", "must not be negative")
+	}
+	if s.PoolTimeout < 0 {
+		invalid("PoolTimeout", "must not be negative")
+	}
+	if s.PoolMaxLifetime < 0 {
+		invalid("PoolMaxLifetime", "must not be negative")
+	}
+	if s.PoolIdleTimeout < 0 {
+		invalid("PoolIdleTimeout", "must not be negative")
+	}
+	switch s.UnknownClassPolicy {
+	case "", UnknownClassFail:
+	case UnknownClassRequeue:
+		if s.UnknownClassRequeues < 1 {
+			invalid("UnknownClassRequeues", "must be at least 1 to requeue jobs of unknown classes, not %d", s.UnknownClassRequeues)
+		}
+	case UnknownClassMove:
+		if s.UnknownClassQueue == "" {
+			invalid("UnknownClassQueue", "must be set to move jobs of unknown classes")
+		}
+	default:
+		invalid("UnknownClassPolicy", "must be one of fail, requeue or move, not %q", s.UnknownClassPolicy)
+	}
+	for _, queue := range s.Queues {
+		if queue == "" || strings.ContainsAny(queue, ", ") {
+			invalid("Queues", "has an invalid queue name %q", queue)
+		}
+	}
+
+	if message := validateURI(s.URI); message != "" {
+		invalid("URI", "%s", message)
+	}
+
+	if (s.TLSClientCertPath == "") != (s.TLSClientKeyPath == "") {
+		invalid("TLSClientCertPath", "and TLSClientKeyPath must be set together")
+	}
+	if s.TLSMinVersion != "" {
+		if _, err := parseTLSVersion(s.TLSMinVersion); err != nil {
+			invalid("TLSMinVersion", "must be one of 1.0, 1.1, 1.2 or 1.3, not %q", s.TLSMinVersion)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// validateURI returns what is wrong with a Redis URI, or an
+// empty string if nothing is.
+func validateURI(uriString string) string {
+	if uriString == "" {
+		return "must not be empty"
+	}
+	uri, err := url.Parse(uriString)
+	if err != nil {
+		return fmt.Sprintf("is malformed: %v", err)
+	}
+
+	switch uri.Scheme {
+	case "redis", "rediss", "redis-cluster":
+		if uri.Host == "" {
+			return fmt.Sprintf("%q has no host", uriString)
+		}
+	case "redis-sentinel":
+		if uri.Host == "" {
+			return fmt.Sprintf("%q has no sentinels", uriString)
+		}
+		if name, _ := parseSentinelPath(uri.Path); name == "" {
+			return fmt.Sprintf("%q has no master name", uriString)
+		}
+	case "unix":
+		if uri.Path == "" {
+			return fmt.Sprintf("%q has no socket path", uriString)
+		}
+	default:
+		return fmt.Sprintf("%q has scheme %q, expected redis, rediss, redis-sentinel, redis-cluster or unix", uriString, uri.Scheme)
+	}
+	return ""
+}
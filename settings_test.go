@@ -0,0 +1,97 @@
+package goworker
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func validTestSettings() WorkerSettings {
+	return WorkerSettings{
+		Queues:      []string{"high"},
+		Interval:    intervalFlag(5 * time.Second),
+		Concurrency: 25,
+		Connections: 2,
+		URI:         "redis://localhost:6379/",
+		Namespace:   "resque:",
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var validateTests = []struct {
+		change func(*WorkerSettings)
+		fields []string
+	}{
+		{func(s *WorkerSettings) {}, nil},
+		{func(s *WorkerSettings) { s.Concurrency = 0 }, []string{"Concurrency"}},
+		{func(s *WorkerSettings) { s.Connections = 0; s.Namespace = "" }, []string{"Connections", "Namespace"}},
+		{func(s *WorkerSettings) { s.Interval = 0 }, []string{"Interval"}},
+		{func(s *WorkerSettings) { s.URI = "http://localhost/" }, []string{"URI"}},
+		{func(s *WorkerSettings) { s.URI = "redis://%zz" }, []string{"URI"}},
+		{func(s *WorkerSettings) { s.URI = "redis-sentinel://localhost:26379/" }, []string{"URI"}},
+		{func(s *WorkerSettings) { s.URI = "unix:///tmp/redis.sock" }, nil},
+		{func(s *WorkerSettings) { s.PoolTimeout = -time.Second; s.StatusTTL = -1 }, []string{"StatusTTL", "PoolTimeout"}},
+		{func(s *WorkerSettings) { s.TLSClientCertPath = "cert.pem" }, []string{"TLSClientCertPath"}},
+		{func(s *WorkerSettings) { s.TLSMinVersion = "1.4" }, []string{"TLSMinVersion"}},
+		{func(s *WorkerSettings) { s.Queues = []string{"a,b"} }, []string{"Queues"}},
+		{func(s *WorkerSettings) { s.PoisonThreshold = -1 }, []string{"PoisonThreshold"}},
+		{func(s *WorkerSettings) { s.UnknownClassPolicy = "drop" }, []string{"UnknownClassPolicy"}},
+		{func(s *WorkerSettings) { s.UnknownClassPolicy = UnknownClassMove }, []string{"UnknownClassQueue"}},
+		{func(s *WorkerSettings) { s.UnknownClassPolicy = UnknownClassRequeue }, []string{"UnknownClassRequeues"}},
+	}
+
+	for i, tt := range validateTests {
+		settings := validTestSettings()
+		tt.change(&settings)
+
+		err := settings.Validate()
+		var fields []string
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			for _, fieldErr := range validationErr.Errors {
+				fields = append(fields, fieldErr.Field)
+			}
+		} else if err != nil {
+			t.Errorf("Validate %d: expected a *ValidationError, actual %v", i, err)
+		}
+		if !reflect.DeepEqual(fields, tt.fields) {
+			t.Errorf("Validate %d: expected invalid %v, actual %v (%v)", i, tt.fields, fields, err)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	var normalizeTests = []struct {
+		settings WorkerSettings
+		queues   []string
+		strict   bool
+		interval time.Duration
+	}{
+		{WorkerSettings{QueuesString: "high,low", IntervalFloat: 2}, []string{"high", "low"}, true, 2 * time.Second},
+		{WorkerSettings{QueuesString: "high=2,low", IntervalFloat: 2}, []string{"high", "low"}, false, 2 * time.Second},
+		{WorkerSettings{Queues: []string{"high"}, Interval: intervalFlag(time.Second)}, []string{"high"}, true, time.Second},
+		{WorkerSettings{Queues: []string{"high"}, QueueWeights: map[string]int{"high": 3}}, []string{"high"}, false, 0},
+	}
+
+	for _, tt := range normalizeTests {
+		settings := tt.settings
+		if err := settings.normalize(); err != nil {
+			t.Errorf("normalize(%+v): error %s", tt.settings, err)
+			continue
+		}
+		if !reflect.DeepEqual([]string(settings.Queues), tt.queues) || settings.IsStrict != tt.strict || time.Duration(settings.Interval) != tt.interval {
+			t.Errorf("normalize(%+v): expected %v %v %v, actual %v %v %v", tt.settings, tt.queues, tt.strict, tt.interval, settings.Queues, settings.IsStrict, time.Duration(settings.Interval))
+		}
+	}
+}
+
+func TestInitValidates(t *testing.T) {
+	defer func(settings WorkerSettings) { workerSettings = settings }(workerSettings)
+	workerSettings.Connections = 0
+
+	if err := Init(); err == nil {
+		Close()
+		t.Errorf("Init: expected an error for zero connections")
+	}
+}
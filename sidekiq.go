@@ -0,0 +1,245 @@
+package goworker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// sidekiqDefaultRetries is how many times Sidekiq
+	// retries a job whose retry option is true.
+	sidekiqDefaultRetries = 25
+
+	// sidekiqDeadMax and sidekiqDeadTimeout bound the dead
+	// set as Sidekiq does.
+	sidekiqDeadMax     = 10000
+	sidekiqDeadTimeout = 180 * 24 * time.Hour
+
+	// sidekiqPollInterval is how often the poller moves due
+	// jobs from the schedule and retry sets to their queues.
+	sidekiqPollInterval = 5 * time.Second
+)
+
+var errorNotSidekiqQueue = errors.New("the queue is not listed in -sidekiq-queues")
+
+// enqueueDueSidekiqScript moves up to 100 jobs whose time
+// has come from a Sidekiq schedule or retry set to the
+// queues named in them.
+var enqueueDueSidekiqScript = redis.NewScript(1, `
+local jobs = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 100)
+local moved = 0
+for _, job in ipairs(jobs) do
+	if redis.call('ZREM', KEYS[1], job) == 1 then
+		local queue = cjson.decode(job).queue
+		redis.call('SADD', ARGV[2] .. 'queues', queue)
+		redis.call('LPUSH', ARGV[2] .. 'queue:' .. queue, job)
+		moved = moved + 1
+	end
+end
+return moved
+`)
+
+// isSidekiqQueue reports whether queue was listed in the
+// -sidekiq-queues flag.
+func isSidekiqQueue(queue string) bool {
+	for _, q := range workerSettings.SidekiqQueues {
+		if q == queue {
+			return true
+		}
+	}
+	return false
+}
+
+// sidekiqKey returns the Redis key called name in the
+// Sidekiq namespace.
+func sidekiqKey(name string) string {
+	return workerSettings.SidekiqNamespace + name
+}
+
+// encodeJob returns job as it is stored on its queue: a
+// Sidekiq job hash for Sidekiq queues, or else the Resque
+// payload.
+func encodeJob(job *Job) ([]byte, error) {
+	if isSidekiqQueue(job.Queue) {
+		return json.Marshal(sidekiqHash(job))
+	}
+	return json.Marshal(job.Payload)
+}
+
+// sidekiqHash returns the Sidekiq job hash for job. A job
+// read from a Sidekiq queue keeps the keys goworker does not
+// know, so that Sidekiq middleware sees them on retries.
+func sidekiqHash(job *Job) map[string]interface{} {
+	hash := job.sidekiq
+	if hash == nil {
+		hash = map[string]interface{}{
+			"retry":      true,
+			"created_at": job.Payload.EnqueuedAt,
+		}
+		job.sidekiq = hash
+	}
+
+	args := job.Payload.Args
+	if args == nil {
+		args = []interface{}{}
+	}
+	hash["class"] = job.Payload.Class
+	hash["args"] = args
+	hash["queue"] = job.Queue
+	hash["jid"] = job.Payload.ID
+	hash["enqueued_at"] = job.Payload.EnqueuedAt
+	if len(job.Payload.Meta) > 0 {
+		hash["meta"] = job.Payload.Meta
+	}
+	return hash
+}
+
+// decodeSidekiqJob reads a Sidekiq job hash popped from
+// queue.
+func decodeSidekiqJob(queue string, buffer []byte) (*Job, error) {
+	decoder := json.NewDecoder(bytes.NewReader(buffer))
+	if workerSettings.UseNumber {
+		decoder.UseNumber()
+	}
+
+	hash := make(map[string]interface{})
+	if err := decoder.Decode(&hash); err != nil {
+		return nil, err
+	}
+
+	job := &Job{Queue: queue, raw: buffer, sidekiq: hash}
+	job.Payload.Class, _ = hash["class"].(string)
+	job.Payload.Args, _ = hash["args"].([]interface{})
+	job.Payload.ID, _ = hash["jid"].(string)
+	job.Payload.EnqueuedAt = jsonNumber(hash["enqueued_at"])
+	job.Payload.Meta, _ = hash["meta"].(map[string]interface{})
+	if count, ok := hash["retry_count"]; ok {
+		// retry_count is 0 after the first failure.
+		job.Payload.Attempt = int(jsonNumber(count)) + 1
+	}
+	return job, nil
+}
+
+// jsonNumber returns a number decoded from JSON, with or
+// without -use-number, or 0 if value is not one.
+func jsonNumber(value interface{}) float64 {
+	switch value := value.(type) {
+	case float64:
+		return value
+	case json.Number:
+		f, _ := value.Float64()
+		return f
+	}
+	return 0
+}
+
+// sidekiqRetries returns how many times a job with the given
+// retry option is retried.
+func sidekiqRetries(retry interface{}) int {
+	switch retry := retry.(type) {
+	case bool:
+		if retry {
+			return sidekiqDefaultRetries
+		}
+		return 0
+	case nil:
+		return sidekiqDefaultRetries
+	}
+	return int(jsonNumber(retry))
+}
+
+// sidekiqRetryDelay returns how long Sidekiq waits before
+// retrying a job that has failed count+1 times.
+func sidekiqRetryDelay(count int) time.Duration {
+	seconds := count*count*count*count + 15 + rand.Intn(10)*(count+1)
+	return time.Duration(seconds) * time.Second
+}
+
+// failSidekiq records the failure of a job from a Sidekiq
+// queue as Sidekiq does. The job is scheduled on the retry
+// set until its retries are used up and then moved to the
+// dead set. A job whose retry option is false is dropped.
+func failSidekiq(conn *RedisConn, job *Job, err error) error {
+	hash := sidekiqHash(job)
+	retries := sidekiqRetries(hash["retry"])
+	if retries == 0 {
+		logger.Infof("Dropping failed Sidekiq job %s, which has retries disabled", job.Payload.ID)
+		return nil
+	}
+
+	now := time.Now()
+	nowFloat := float64(now.UnixNano()) / float64(time.Second)
+	count := 0
+	if previous, ok := hash["retry_count"]; ok {
+		count = int(jsonNumber(previous)) + 1
+		hash["retried_at"] = nowFloat
+	} else {
+		hash["failed_at"] = nowFloat
+	}
+	hash["retry_count"] = count
+	hash["error_message"] = err.Error()
+	hash["error_class"] = exceptionName(err)
+
+	buffer, errMarshal := json.Marshal(hash)
+	if errMarshal != nil {
+		return errMarshal
+	}
+
+	if count < retries {
+		at := now.Add(sidekiqRetryDelay(count))
+		return conn.Send("ZADD", sidekiqKey("retry"), float64(at.UnixNano())/float64(time.Second), buffer)
+	}
+	if dead, ok := hash["dead"].(bool); ok && !dead {
+		return nil
+	}
+	conn.Send("ZADD", sidekiqKey("dead"), nowFloat, buffer)
+	conn.Send("ZREMRANGEBYSCORE", sidekiqKey("dead"), "-inf", float64(now.Add(-sidekiqDeadTimeout).Unix()))
+	return conn.Send("ZREMRANGEBYRANK", sidekiqKey("dead"), 0, -sidekiqDeadMax-1)
+}
+
+// enqueueDueSidekiqJobs moves the jobs whose time has come
+// from the Sidekiq schedule and retry sets to their queues.
+func enqueueDueSidekiqJobs(conn *RedisConn) error {
+	now := float64(time.Now().UnixNano()) / float64(time.Second)
+	for _, set := range []string{"schedule", "retry"} {
+		if _, err := conn.runScript(enqueueDueSidekiqScript, sidekiqKey(set), now, workerSettings.SidekiqNamespace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnqueueSidekiqAt adds job to the Sidekiq schedule set, to
+// be pushed onto its queue at the given time by a Sidekiq
+// process or a goworker poller. The queue must be listed in
+// -sidekiq-queues.
+func EnqueueSidekiqAt(job *Job, at time.Time) error {
+	if err := Init(); err != nil {
+		return err
+	}
+	if !isSidekiqQueue(job.Queue) {
+		return errorNotSidekiqQueue
+	}
+	if err := job.Payload.stamp(); err != nil {
+		return err
+	}
+
+	buffer, err := encodeJob(job)
+	if err != nil {
+		return err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return err
+	}
+	defer PutConn(conn)
+
+	_, err = conn.Do("ZADD", sidekiqKey("schedule"), float64(at.UnixNano())/float64(time.Second), buffer)
+	return err
+}
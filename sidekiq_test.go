@@ -0,0 +1,163 @@
+package goworker
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func setUpSidekiqTest(t *testing.T) *RedisConn {
+	workerSettings.Queues = []string{"sidekiqJobs"}
+	workerSettings.SidekiqQueues = []string{"sidekiqJobs"}
+	workerSettings.SidekiqNamespace = "sidekiqtest:"
+	t.Cleanup(func() {
+		workerSettings.SidekiqQueues = nil
+		workerSettings.SidekiqNamespace = ""
+	})
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	t.Cleanup(Close)
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	t.Cleanup(func() { PutConn(conn) })
+
+	conn.Do("DEL", sidekiqKey("queue:sidekiqJobs"), sidekiqKey("queues"), sidekiqKey("retry"), sidekiqKey("dead"), sidekiqKey("schedule"))
+	return conn
+}
+
+func TestSidekiqEnqueue(t *testing.T) {
+	conn := setUpSidekiqTest(t)
+
+	job := &Job{Queue: "sidekiqJobs", Payload: Payload{Class: "HardWorker", Args: []interface{}{"bob", 5}}}
+	if err := Enqueue(job); err != nil {
+		t.Fatalf("Enqueue: error %s", err)
+	}
+
+	buffer, err := redis.Bytes(conn.Do("LINDEX", sidekiqKey("queue:sidekiqJobs"), 0))
+	if err != nil {
+		t.Fatalf("LINDEX: error %s", err)
+	}
+	hash := make(map[string]interface{})
+	if err := json.Unmarshal(buffer, &hash); err != nil {
+		t.Fatalf("Unmarshal: error %s", err)
+	}
+	for _, key := range []string{"class", "args", "queue", "jid", "retry", "created_at", "enqueued_at"} {
+		if _, ok := hash[key]; !ok {
+			t.Errorf("Enqueue: expected %q in the Sidekiq job %s", key, buffer)
+		}
+	}
+	if member, _ := redis.Bool(conn.Do("SISMEMBER", sidekiqKey("queues"), "sidekiqJobs")); !member {
+		t.Errorf("Enqueue: expected the queue in the Sidekiq queues set")
+	}
+
+	p := &poller{selector: NewStrictQueueSelector(workerSettings.Queues)}
+	popped, err := p.getJob(conn)
+	if err != nil || popped == nil {
+		t.Fatalf("getJob: expected the job, actual %v %v", popped, err)
+	}
+	if popped.Payload.Class != "HardWorker" || popped.Payload.ID != job.Payload.ID || len(popped.Payload.Args) != 2 || popped.Payload.Attempt != 0 {
+		t.Errorf("getJob: unexpected payload %+v", popped.Payload)
+	}
+}
+
+func TestFailSidekiq(t *testing.T) {
+	conn := setUpSidekiqTest(t)
+
+	var failTests = []struct {
+		hash    map[string]interface{}
+		set     string
+		count   float64
+		attempt int
+	}{
+		{map[string]interface{}{"retry": true}, "retry", 0, 0},
+		{map[string]interface{}{"retry": true, "retry_count": 3.0}, "retry", 4, 4},
+		{map[string]interface{}{"retry": 2.0, "retry_count": 1.0}, "dead", 2, 2},
+		{map[string]interface{}{"retry": 2.0, "retry_count": 1.0, "dead": false}, "", 0, 2},
+		{map[string]interface{}{"retry": false}, "", 0, 0},
+	}
+
+	for _, tt := range failTests {
+		conn.Do("DEL", sidekiqKey("retry"), sidekiqKey("dead"))
+
+		tt.hash["class"] = "HardWorker"
+		tt.hash["args"] = []interface{}{}
+		tt.hash["jid"] = "0123456789abcdef01234567"
+		tt.hash["queue"] = "sidekiqJobs"
+		buffer, _ := json.Marshal(tt.hash)
+		job, err := decodeSidekiqJob("sidekiqJobs", buffer)
+		if err != nil {
+			t.Fatalf("decodeSidekiqJob: error %s", err)
+		}
+		if job.Payload.Attempt != tt.attempt {
+			t.Errorf("decodeSidekiqJob(%s): expected attempt %d, actual %d", buffer, tt.attempt, job.Payload.Attempt)
+		}
+
+		if err := failSidekiq(conn, job, errors.New("boom")); err != nil {
+			t.Fatalf("failSidekiq: error %s", err)
+		}
+		conn.Do("")
+
+		for _, set := range []string{"retry", "dead"} {
+			entries, _ := redis.ByteSlices(conn.Do("ZRANGE", sidekiqKey(set), 0, -1))
+			if set != tt.set {
+				if len(entries) != 0 {
+					t.Errorf("failSidekiq(%s): expected nothing in %s, actual %s", buffer, set, entries)
+				}
+				continue
+			}
+			if len(entries) != 1 {
+				t.Errorf("failSidekiq(%s): expected one job in %s, actual %d", buffer, set, len(entries))
+				continue
+			}
+			failed := make(map[string]interface{})
+			json.Unmarshal(entries[0], &failed)
+			if failed["retry_count"] != tt.count || failed["error_message"] != "boom" || failed["jid"] != tt.hash["jid"] {
+				t.Errorf("failSidekiq(%s): unexpected job in %s: %s", buffer, set, entries[0])
+			}
+		}
+	}
+}
+
+func TestEnqueueDueSidekiqJobs(t *testing.T) {
+	conn := setUpSidekiqTest(t)
+
+	due := &Job{Queue: "sidekiqJobs", Payload: Payload{Class: "Due", Args: []interface{}{}}}
+	later := &Job{Queue: "sidekiqJobs", Payload: Payload{Class: "Later", Args: []interface{}{}}}
+	if err := EnqueueSidekiqAt(due, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("EnqueueSidekiqAt: error %s", err)
+	}
+	if err := EnqueueSidekiqAt(later, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("EnqueueSidekiqAt: error %s", err)
+	}
+	if err := EnqueueSidekiqAt(&Job{Queue: "resqueJobs"}, time.Now()); err != errorNotSidekiqQueue {
+		t.Errorf("EnqueueSidekiqAt: expected %v, actual %v", errorNotSidekiqQueue, err)
+	}
+
+	if err := enqueueDueSidekiqJobs(conn); err != nil {
+		t.Fatalf("enqueueDueSidekiqJobs: error %s", err)
+	}
+
+	var classes []string
+	for {
+		buffer, err := redis.Bytes(conn.Do("RPOP", sidekiqKey("queue:sidekiqJobs")))
+		if err != nil {
+			break
+		}
+		job, _ := decodeSidekiqJob("sidekiqJobs", buffer)
+		classes = append(classes, job.Payload.Class)
+	}
+	if !reflect.DeepEqual(classes, []string{"Due"}) {
+		t.Errorf("enqueueDueSidekiqJobs: expected [Due] on the queue, actual %v", classes)
+	}
+	if n, _ := redis.Int(conn.Do("ZCARD", sidekiqKey("schedule"))); n != 1 {
+		t.Errorf("enqueueDueSidekiqJobs: expected one job left scheduled, actual %d", n)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+// +build !windows
+
+package goworker
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyScale adds a worker to s on TTIN and removes one on
+// TTOU until the returned function is called.
+func notifyScale(s *scaler) func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+
+	signal.Notify(signals, syscall.SIGTTIN, syscall.SIGTTOU)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				delta := 1
+				if sig == syscall.SIGTTOU {
+					delta = -1
+				}
+				if err := s.adjust(delta); err != nil {
+					logger.Errorf("Error handling %v: %v", sig, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		signalStop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package goworker
+
+// notifyScale does nothing on Windows, which has no TTIN
+// or TTOU signals.
+func notifyScale(s *scaler) func() {
+	return func() {}
+}
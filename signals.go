@@ -6,6 +6,10 @@
 // $CONCURRENCY jobs currently running, which
 // will continue to run until they are finished.
 //
+// Send a TTIN signal to start one more worker, or a
+// TTOU signal to stop one. A stopped worker finishes
+// its current job before it shuts down.
+//
 // Failure Modes
 //
 // Like Resque, goworker makes no guarantees
@@ -48,7 +52,7 @@ func signals() <-chan bool {
 	quit := make(chan bool)
 
 	go func() {
-		signals := make(chan os.Signal)
+		signals := make(chan os.Signal, 1)
 		defer close(signals)
 
 		signal.Notify(signals, syscall.SIGQUIT, syscall.SIGTERM, os.Interrupt)
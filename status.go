@@ -0,0 +1,244 @@
+package goworker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"golang.org/x/net/context"
+)
+
+var (
+	// ErrJobKilled is returned by SetProgress once KillJob
+	// has been called for the job. A worker function should
+	// stop and return it, which marks the job killed rather
+	// than failed.
+	ErrJobKilled = errors.New("job killed")
+
+	errorStatusNotFound = errors.New("no status for that job")
+	errorNotTracked     = errors.New("job status is not tracked")
+)
+
+// Job states stored by the status subsystem, the same as
+// those of resque-status.
+const (
+	StatusQueued    = "queued"
+	StatusWorking   = "working"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusKilled    = "killed"
+)
+
+// Status is the state of a job enqueued with
+// EnqueueWithStatus, stored in the same JSON format and
+// under the same keys as resque-status so that its tools
+// can read it.
+type Status struct {
+	UUID    string `json:"uuid"`
+	Status  string `json:"status"`
+	Time    int64  `json:"time"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message,omitempty"`
+	Num     int    `json:"num,omitempty"`
+	Total   int    `json:"total,omitempty"`
+}
+
+// EnqueueWithStatus enqueues job with its status tracked,
+// and returns the job ID under which its status is stored.
+// The status expires -status-ttl seconds after it last
+// changed.
+func EnqueueWithStatus(job *Job) (string, error) {
+	if err := Init(); err != nil {
+		return "", err
+	}
+
+	if job.Payload.Meta == nil {
+		job.Payload.Meta = make(map[string]interface{})
+	}
+	job.Payload.Meta["status"] = true
+	if err := job.Payload.stamp(); err != nil {
+		return "", err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return "", err
+	}
+	err = setStatus(conn, &Status{
+		UUID:   job.Payload.ID,
+		Status: StatusQueued,
+		Name:   fmt.Sprintf("%s(%v)", job.Payload.Class, job.Payload.Args),
+	})
+	PutConn(conn)
+	if err != nil {
+		return "", err
+	}
+
+	return job.Payload.ID, Enqueue(job)
+}
+
+// JobStatus returns the status of the job with the given
+// ID.
+func JobStatus(id string) (*Status, error) {
+	if err := Init(); err != nil {
+		return nil, err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return nil, err
+	}
+	defer PutConn(conn)
+
+	return getStatus(conn, id)
+}
+
+// KillJob asks the job with the given ID to stop. A queued
+// job is killed as soon as a worker picks it up, and a
+// running job sees the request the next time it calls
+// SetProgress.
+func KillJob(id string) error {
+	if err := Init(); err != nil {
+		return err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return err
+	}
+	defer PutConn(conn)
+
+	_, err = conn.Do("SADD", fmt.Sprintf("%s_kill", workerSettings.Namespace), id)
+	return err
+}
+
+// SetProgress records that the job run with ctx is at num
+// of total, with an optional message. It returns
+// ErrJobKilled if the job was asked to stop, and an error
+// if the job's status is not tracked.
+func SetProgress(ctx context.Context, num, total int, message string) error {
+	job, ok := JobFromContext(ctx)
+	if !ok || !isStatusTracked(job) {
+		return errorNotTracked
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return err
+	}
+	defer PutConn(conn)
+
+	if killed, err := isKillRequested(conn, job.Payload.ID); err != nil {
+		return err
+	} else if killed {
+		return ErrJobKilled
+	}
+
+	status, err := getStatus(conn, job.Payload.ID)
+	if err == errorStatusNotFound {
+		status = &Status{UUID: job.Payload.ID}
+	} else if err != nil {
+		return err
+	}
+	status.Status = StatusWorking
+	status.Num = num
+	status.Total = total
+	status.Message = message
+	return setStatus(conn, status)
+}
+
+func isStatusTracked(job *Job) bool {
+	tracked, _ := job.Payload.Meta["status"].(bool)
+	return tracked
+}
+
+func isKillRequested(conn *RedisConn, id string) (bool, error) {
+	return redis.Bool(conn.Do("SISMEMBER", fmt.Sprintf("%s_kill", workerSettings.Namespace), id))
+}
+
+func getStatus(conn *RedisConn, id string) (*Status, error) {
+	buffer, err := redis.Bytes(conn.Do("GET", fmt.Sprintf("%sstatus:%s", workerSettings.Namespace, id)))
+	if err == redis.ErrNil {
+		return nil, errorStatusNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{}
+	if err := json.Unmarshal(buffer, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func setStatus(conn *RedisConn, status *Status) error {
+	status.Time = time.Now().Unix()
+	buffer, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%sstatus:%s", workerSettings.Namespace, status.UUID)
+	if workerSettings.StatusTTL > 0 {
+		conn.Send("SET", key, buffer, "EX", workerSettings.StatusTTL)
+	} else {
+		conn.Send("SET", key, buffer)
+	}
+	conn.Send("ZADD", fmt.Sprintf("%s_statuses", workerSettings.Namespace), status.Time, status.UUID)
+	_, err = conn.Do("")
+	return err
+}
+
+// updateStatus moves the tracked status of job to state,
+// keeping its name and progress.
+func updateStatus(conn *RedisConn, job *Job, state string, message string) error {
+	if !isStatusTracked(job) {
+		return nil
+	}
+
+	status, err := getStatus(conn, job.Payload.ID)
+	if err == errorStatusNotFound {
+		status = &Status{UUID: job.Payload.ID, Name: job.Payload.Class}
+	} else if err != nil {
+		return err
+	}
+	status.Status = state
+	if message != "" {
+		status.Message = message
+	}
+	if state == StatusKilled {
+		conn.Send("SREM", fmt.Sprintf("%s_kill", workerSettings.Namespace), job.Payload.ID)
+	}
+	return setStatus(conn, status)
+}
+
+// startStatus marks job working, or returns ErrJobKilled if
+// it was killed while queued.
+func startStatus(conn *RedisConn, job *Job) error {
+	if !isStatusTracked(job) {
+		return nil
+	}
+
+	if killed, err := isKillRequested(conn, job.Payload.ID); err != nil {
+		return err
+	} else if killed {
+		return ErrJobKilled
+	}
+	return updateStatus(conn, job, StatusWorking, "")
+}
+
+// finishStatus marks job completed, failed or killed
+// according to the error it finished with.
+func finishStatus(conn *RedisConn, job *Job, err error) error {
+	switch {
+	case err == nil:
+		return updateStatus(conn, job, StatusCompleted, "")
+	case errors.Is(err, ErrJobKilled):
+		return updateStatus(conn, job, StatusKilled, "")
+	default:
+		return updateStatus(conn, job, StatusFailed, err.Error())
+	}
+}
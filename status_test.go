@@ -0,0 +1,97 @@
+package goworker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"golang.org/x/net/context"
+)
+
+func TestJobStatus(t *testing.T) {
+	workerSettings.Queues = []string{"statusJobs"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+
+	var statusTests = []struct {
+		kill     bool
+		err      error
+		expected string
+		message  string
+	}{
+		{false, nil, StatusCompleted, "halfway"},
+		{false, errors.New("broken"), StatusFailed, "broken"},
+		{true, nil, StatusKilled, ""},
+	}
+
+	w := &worker{}
+	failedKey := fmt.Sprintf("%sfailed", workerSettings.Namespace)
+	for _, tt := range statusTests {
+		conn.Do("DEL", queueKey("statusJobs"), failedKey)
+
+		id, err := EnqueueWithStatus(&Job{Queue: "statusJobs", Payload: Payload{Class: "Tracked", Args: []interface{}{}}})
+		if err != nil {
+			t.Fatalf("EnqueueWithStatus: error %s", err)
+		}
+		if status, err := JobStatus(id); err != nil || status.Status != StatusQueued {
+			t.Errorf("JobStatus(%s): expected %s, actual %v (%v)", id, StatusQueued, status, err)
+		}
+		if tt.kill {
+			if err := KillJob(id); err != nil {
+				t.Fatalf("KillJob: error %s", err)
+			}
+		}
+
+		job := popTestJob(t, conn, "statusJobs")
+		if job == nil {
+			t.Fatalf("JobStatus: expected a job on the queue")
+		}
+		ran := false
+		w.run(job, func(ctx context.Context, job *Job) error {
+			ran = true
+			if err := SetProgress(ctx, 1, 2, "halfway"); err != nil {
+				return err
+			}
+			return tt.err
+		})
+		if ran == tt.kill {
+			t.Errorf("JobStatus(%s): expected job to run %v, actual %v", id, !tt.kill, ran)
+		}
+
+		status, err := JobStatus(id)
+		if err != nil {
+			t.Fatalf("JobStatus(%s): error %s", id, err)
+		}
+		if status.Status != tt.expected || status.Message != tt.message {
+			t.Errorf("JobStatus(%s): expected %s %q, actual %s %q", id, tt.expected, tt.message, status.Status, status.Message)
+		}
+		if !tt.kill && (status.Num != 1 || status.Total != 2) {
+			t.Errorf("JobStatus(%s): expected progress 1/2, actual %d/%d", id, status.Num, status.Total)
+		}
+		// Only a job that returned an error of its own is failed;
+		// a killed one is not.
+		expectedFailed := 0
+		if tt.expected == StatusFailed {
+			expectedFailed = 1
+		}
+		if failed, _ := redis.Int(conn.Do("LLEN", failedKey)); failed != expectedFailed {
+			t.Errorf("JobStatus(%s): expected %d failed, actual %d", id, expectedFailed, failed)
+		}
+	}
+}
+
+func TestSetProgressUntracked(t *testing.T) {
+	job := &Job{Queue: "statusJobs", Payload: Payload{Class: "Untracked"}}
+	if err := SetProgress(newJobContext(job), 1, 2, ""); err != errorNotTracked {
+		t.Errorf("SetProgress: expected %v, actual %v", errorNotTracked, err)
+	}
+}
@@ -0,0 +1,115 @@
+package goworker
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// PanicError is the error a job fails with when its worker
+// function, or a sub-task it started with Go, panics. The
+// stack of the panicking goroutine is recorded as the
+// backtrace of the failure.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprint(e.Value)
+}
+
+// newPanicError returns the error for the panic value r. It
+// must be called from the deferred function that recovered
+// r, so that the stack is that of the panic.
+func newPanicError(r interface{}) *PanicError {
+	return &PanicError{Value: r, Stack: debug.Stack()}
+}
+
+// backtrace returns the lines of the stack of err if it is a
+// *PanicError.
+func backtrace(err error) []string {
+	var panicError *PanicError
+	if !errors.As(err, &panicError) {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(string(panicError.Stack), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+type subtasksContextKey struct{}
+
+// subtasks tracks the sub-tasks a job started with Go.
+type subtasks struct {
+	running sync.WaitGroup
+	mutex   sync.Mutex
+	err     error
+}
+
+// newSubtasksContext returns ctx carrying tasks, for Go to
+// add sub-tasks to.
+func newSubtasksContext(ctx context.Context, tasks *subtasks) context.Context {
+	return context.WithValue(ctx, subtasksContextKey{}, tasks)
+}
+
+// fail records err, keeping the first error recorded.
+func (s *subtasks) fail(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// wait waits for the sub-tasks to return, and returns the
+// error of the first that panicked.
+func (s *subtasks) wait() error {
+	s.running.Wait()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.err
+}
+
+// Go runs fn in a new goroutine as a sub-task of the job
+// whose worker function was called with ctx, as functions
+// registered with RegisterContext are. The job does
+// not finish until its sub-tasks have returned, however long
+// that takes, and is not cancelled while it waits, so fn must
+// return on its own; one that blocks forever holds its worker
+// forever, and keeps a stopping process from exiting. If a
+// sub-task panics, the panic is recovered instead of
+// crashing the process, and the job fails with a
+// *PanicError holding the stack of the sub-task. Outside of
+// a job, the panic is recovered and logged.
+func Go(ctx context.Context, fn func()) {
+	tasks, _ := ctx.Value(subtasksContextKey{}).(*subtasks)
+	if tasks != nil {
+		tasks.running.Add(1)
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err := newPanicError(r)
+				if tasks != nil {
+					tasks.fail(err)
+				} else {
+					logger.Criticalf("Recovered from panic in sub-task outside of a job: %v\n%s", err, err.Stack)
+				}
+			}
+			if tasks != nil {
+				tasks.running.Done()
+			}
+		}()
+		fn()
+	}()
+}
@@ -0,0 +1,120 @@
+package goworker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"golang.org/x/net/context"
+)
+
+func TestGo(t *testing.T) {
+	workerSettings.Queues = []string{"subtasksQueue"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+	failedKey := fmt.Sprintf("%sfailed", workerSettings.Namespace)
+
+	w, err := newWorker("1", workerSettings.Queues)
+	if err != nil {
+		t.Fatalf("newWorker: error %s", err)
+	}
+
+	var goTests = []struct {
+		subtask func()
+		failed  bool
+	}{
+		{func() { time.Sleep(10 * time.Millisecond) }, false},
+		{func() { time.Sleep(10 * time.Millisecond); panic("subtask exploded") }, true},
+	}
+
+	for i, tt := range goTests {
+		conn.Do("DEL", failedKey)
+
+		returned := false
+		job := &Job{Queue: "subtasksQueue", Payload: Payload{Class: "Spawner", Args: []interface{}{}, ID: fmt.Sprintf("subtasks%d", i)}}
+		w.run(job, func(ctx context.Context, job *Job) error {
+			Go(ctx, func() {
+				defer func() { returned = true }()
+				tt.subtask()
+			})
+			return nil
+		})
+		if !returned {
+			t.Errorf("run(%d): expected the job to wait for its sub-task", i)
+		}
+
+		buffer, err := redis.Bytes(conn.Do("LINDEX", failedKey, 0))
+		if !tt.failed {
+			if err != redis.ErrNil {
+				t.Errorf("run(%d): expected no failure, actual %s %v", i, buffer, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("run(%d): expected a failure, error %s", i, err)
+		}
+		var entry failure
+		json.Unmarshal(buffer, &entry)
+		if entry.Error != "subtask exploded" || entry.Payload.ID != job.Payload.ID {
+			t.Errorf("run(%d): unexpected failure %s", i, buffer)
+		}
+		if !strings.Contains(strings.Join(entry.Backtrace, "\n"), "subtasks_test.go") {
+			t.Errorf("run(%d): expected the stack of the sub-task in the backtrace, actual %v", i, entry.Backtrace)
+		}
+	}
+}
+
+func TestGoFromRegisterContext(t *testing.T) {
+	workerSettings.Queues = []string{"subtasksQueue"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+	defer func() {
+		workers.Lock()
+		delete(workers.workers, "PlainSpawner")
+		workers.Unlock()
+	}()
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+	failedKey := fmt.Sprintf("%sfailed", workerSettings.Namespace)
+	conn.Do("DEL", failedKey)
+
+	RegisterContext("PlainSpawner", func(ctx context.Context, queue string, args ...interface{}) error {
+		Go(ctx, func() { panic("plain subtask exploded") })
+		return nil
+	})
+	jobFunc, _ := workers.Get("PlainSpawner")
+
+	w, err := newWorker("1", workerSettings.Queues)
+	if err != nil {
+		t.Fatalf("newWorker: error %s", err)
+	}
+	job := &Job{Queue: "subtasksQueue", Payload: Payload{Class: "PlainSpawner", Args: []interface{}{}, ID: "plainsubtasks"}}
+	w.run(job, jobFunc)
+
+	buffer, err := redis.Bytes(conn.Do("LINDEX", failedKey, 0))
+	if err != nil {
+		t.Fatalf("RegisterContext: expected a failure, error %s", err)
+	}
+	var entry failure
+	json.Unmarshal(buffer, &entry)
+	if entry.Error != "plain subtask exploded" || entry.Payload.ID != job.Payload.ID {
+		t.Errorf("RegisterContext: unexpected failure %s", buffer)
+	}
+}
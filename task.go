@@ -0,0 +1,79 @@
+package goworker
+
+import (
+	"errors"
+
+	"golang.org/x/net/context"
+)
+
+var (
+	errorNoQueue = errors.New("no queue given and none registered for the class")
+)
+
+// Task is a single definition of a class whose jobs take an
+// argument of type T, shared by the code that enqueues the
+// jobs and the code that works them. Arguments are encoded
+// by Enqueue with the same codec RegisterTyped decodes them
+// with, so both sides agree on their shape.
+//
+//	var chargeOrder = goworker.NewTask[Order]("ChargeOrder", "orders")
+//
+//	func init() {
+//		chargeOrder.Register(func(ctx context.Context, queue string, order Order) error {
+//			return charge(order.ID, order.Amount)
+//		})
+//	}
+//
+//	err := chargeOrder.Enqueue(ctx, Order{ID: 1, Amount: 9.5})
+type Task[T any] struct {
+	Class string
+	Queue string
+}
+
+// NewTask returns a task for class. If queue is empty, jobs
+// are enqueued on the queue the class was registered with
+// through another Task.
+func NewTask[T any](class string, queue string) *Task[T] {
+	return &Task[T]{
+		Class: class,
+		Queue: queue,
+	}
+}
+
+// Register registers worker as the typed worker function of
+// the task's class, and makes the task's queue the default
+// queue of the class.
+func (t *Task[T]) Register(worker func(ctx context.Context, queue string, arg T) error) {
+	RegisterTyped(t.Class, worker)
+	if t.Queue != "" {
+		workers.SetQueue(t.Class, t.Queue)
+	}
+}
+
+// Enqueue pushes a job with arg onto the task's queue.
+func (t *Task[T]) Enqueue(ctx context.Context, arg T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	queue := t.Queue
+	if queue == "" {
+		var ok bool
+		if queue, ok = workers.Queue(t.Class); !ok || queue == "" {
+			return errorNoQueue
+		}
+	}
+
+	args, err := encodeArg(arg)
+	if err != nil {
+		return err
+	}
+
+	return Enqueue(&Job{
+		Queue: queue,
+		Payload: Payload{
+			Class: t.Class,
+			Args:  args,
+		},
+	})
+}
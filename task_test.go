@@ -0,0 +1,53 @@
+package goworker
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestTaskEnqueue(t *testing.T) {
+	queueName := "taskQueue"
+	workerSettings.Queues = []string{queueName}
+	workerSettings.UseNumber = true
+	workerSettings.ExitOnComplete = true
+
+	defer func() {
+		workers.Lock()
+		delete(workers.workers, "TaskClass")
+		delete(workers.queues, "TaskClass")
+		workers.Unlock()
+	}()
+
+	producer := NewTask[typedArg]("TaskClass", "")
+	if err := producer.Enqueue(context.Background(), typedArg{ID: 1}); err != errorNoQueue {
+		t.Errorf("Task: expected err %v before registration, actual err %v", errorNoQueue, err)
+	}
+
+	var actual typedArg
+	var actualQueue string
+	var actualJob *Job
+	NewTask[typedArg]("TaskClass", queueName).Register(func(ctx context.Context, queue string, arg typedArg) error {
+		actual = arg
+		actualQueue = queue
+		actualJob, _ = JobFromContext(ctx)
+		return nil
+	})
+
+	expected := typedArg{ID: 9007199254740993, Name: "task"}
+	if err := producer.Enqueue(context.Background(), expected); err != nil {
+		t.Fatalf("Task: enqueue error %s", err)
+	}
+	if err := Work(); err != nil {
+		t.Fatalf("Task: work error %s", err)
+	}
+	if actual != expected {
+		t.Errorf("Task: expected %v, actual %v", expected, actual)
+	}
+	if actualQueue != queueName {
+		t.Errorf("Task: expected queue %s, actual %s", queueName, actualQueue)
+	}
+	if actualJob == nil || actualJob.Payload.ID == "" || actualJob.Payload.Attempt != 1 {
+		t.Errorf("Task: expected job with ID on attempt 1 in context, actual %+v", actualJob)
+	}
+}
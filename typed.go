@@ -0,0 +1,120 @@
+package goworker
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"golang.org/x/net/context"
+)
+
+// DecodeError is the error a job fails with when its
+// arguments cannot be decoded into the type expected by a
+// worker function registered with RegisterTyped. It is
+// recorded in the failed list with the exception
+// DecodeError.
+type DecodeError struct {
+	Class string
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("cannot decode arguments of %s: %v", e.Class, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// RegisterTyped registers a worker function that receives
+// its arguments decoded with encoding/json into a value of
+// type T. If T is a slice or array, other than []byte, it
+// is decoded from the whole argument array, for positional
+// arguments. Otherwise the job must have exactly one
+// argument, such as a JSON object enqueued by
+// Resque.enqueue(MyClass, {...}), which is decoded into T.
+// If decoding fails, the worker function is not called and
+// the job fails with a *DecodeError.
+func RegisterTyped[T any](class string, worker func(ctx context.Context, queue string, arg T) error) {
+	workers.Add(class, func(ctx context.Context, job *Job) error {
+		var arg T
+		if err := decodeArg(job, &arg); err != nil {
+			return &DecodeError{Class: class, Err: err}
+		}
+		return worker(ctx, job.Queue, arg)
+	})
+}
+
+// encodeArg returns the job arguments for arg, encoded so
+// that decodeArg turns them back into the same value.
+func encodeArg(arg interface{}) ([]interface{}, error) {
+	buffer, err := json.Marshal(arg)
+	if err != nil {
+		return nil, err
+	}
+	if arg == nil || !isPositional(reflect.TypeOf(arg)) {
+		return []interface{}{json.RawMessage(buffer)}, nil
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(buffer, &elements); err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(elements))
+	for i, element := range elements {
+		args[i] = element
+	}
+	return args, nil
+}
+
+// isPositional reports whether a value of type t is decoded
+// from the whole argument array rather than from a single
+// argument.
+func isPositional(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Array:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// decodeArg decodes the arguments of job into v.
+func decodeArg(job *Job, v interface{}) error {
+	var args json.RawMessage
+	if job.raw != nil {
+		var payload struct {
+			Args json.RawMessage `json:"args"`
+		}
+		if err := json.Unmarshal(job.raw, &payload); err != nil {
+			return err
+		}
+		args = payload.Args
+		if args == nil {
+			args = json.RawMessage("[]")
+		}
+	} else {
+		buffer, err := json.Marshal(job.Payload.Args)
+		if err != nil {
+			return err
+		}
+		args = buffer
+	}
+
+	if isPositional(reflect.TypeOf(v)) {
+		return json.Unmarshal(args, v)
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(args, &elements); err != nil {
+		return err
+	}
+	if len(elements) != 1 {
+		return fmt.Errorf("expected 1 argument, got %d", len(elements))
+	}
+	return json.Unmarshal(elements[0], v)
+}
@@ -0,0 +1,159 @@
+package goworker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+type typedArg struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+var decodeArgTests = []struct {
+	job      *Job
+	expected typedArg
+	err      bool
+}{
+	{
+		&Job{raw: []byte(`{"class":"Typed","args":[{"id":9007199254740993,"name":"a"}]}`)},
+		typedArg{ID: 9007199254740993, Name: "a"},
+		false,
+	},
+	{
+		&Job{Payload: Payload{Args: []interface{}{map[string]interface{}{"id": 1, "name": "b"}}}},
+		typedArg{ID: 1, Name: "b"},
+		false,
+	},
+	{
+		&Job{raw: []byte(`{"class":"Typed","args":[{"id":"1"}]}`)},
+		typedArg{},
+		true,
+	},
+	{
+		&Job{raw: []byte(`{"class":"Typed","args":[1,2]}`)},
+		typedArg{},
+		true,
+	},
+}
+
+func TestDecodeArg(t *testing.T) {
+	for _, tt := range decodeArgTests {
+		var actual typedArg
+		err := decodeArg(tt.job, &actual)
+		if (err != nil) != tt.err {
+			t.Errorf("decodeArg(%s): expected err %v, actual err %v", tt.job.raw, tt.err, err)
+		}
+		if !tt.err && actual != tt.expected {
+			t.Errorf("decodeArg(%s): expected %v, actual %v", tt.job.raw, tt.expected, actual)
+		}
+	}
+}
+
+func TestDecodeArgPositional(t *testing.T) {
+	var actual []int
+	job := &Job{raw: []byte(`{"class":"Typed","args":[1,2,3]}`)}
+	if err := decodeArg(job, &actual); err != nil {
+		t.Fatalf("decodeArg(%s): error %s", job.raw, err)
+	}
+	if fmt.Sprint(actual) != "[1 2 3]" {
+		t.Errorf("decodeArg(%s): expected [1 2 3], actual %v", job.raw, actual)
+	}
+}
+
+var decodeArgShapeTests = []struct {
+	raw      string
+	actual   func() interface{}
+	expected string
+	err      bool
+}{
+	{`{"args":[5]}`, func() interface{} { return &[]int{} }, "&[5]", false},
+	{`{"args":[[1,2]]}`, func() interface{} { return &[]interface{}{} }, "&[[1 2]]", false},
+	{`{"args":[[1,2]]}`, func() interface{} { return &[][]int{} }, "&[[1 2]]", false},
+	{`{"args":[1,2]}`, func() interface{} { return &[2]int{} }, "&[1 2]", false},
+	{`{"args":["aGk="]}`, func() interface{} { return &[]byte{} }, "&[104 105]", false},
+	{`{"args":[[1,2]]}`, func() interface{} { return new(interface{}) }, "", false},
+	{`{"args":[]}`, func() interface{} { return new(string) }, "", true},
+	{`{"args":["a","b"]}`, func() interface{} { return new(string) }, "", true},
+}
+
+func TestDecodeArgShape(t *testing.T) {
+	for _, tt := range decodeArgShapeTests {
+		actual := tt.actual()
+		err := decodeArg(&Job{raw: []byte(tt.raw)}, actual)
+		if (err != nil) != tt.err {
+			t.Errorf("decodeArg(%s, %T): expected err %v, actual err %v", tt.raw, actual, tt.err, err)
+		}
+		if tt.expected != "" && fmt.Sprint(actual) != tt.expected {
+			t.Errorf("decodeArg(%s, %T): expected %s, actual %v", tt.raw, actual, tt.expected, actual)
+		}
+	}
+}
+
+func TestEncodeArgPositional(t *testing.T) {
+	expected := []int{4, 5}
+	args, err := encodeArg(expected)
+	if err != nil {
+		t.Fatalf("encodeArg(%v): error %s", expected, err)
+	}
+	if len(args) != 2 {
+		t.Errorf("encodeArg(%v): expected 2 arguments, actual %d", expected, len(args))
+	}
+
+	var actual []int
+	if err := decodeArg(&Job{Payload: Payload{Args: args}}, &actual); err != nil {
+		t.Fatalf("decodeArg(%v): error %s", args, err)
+	}
+	if fmt.Sprint(actual) != "[4 5]" {
+		t.Errorf("encodeArg: expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestRegisterTyped(t *testing.T) {
+	var actual typedArg
+	RegisterTyped("TypedClass", func(ctx context.Context, queue string, arg typedArg) error {
+		actual = arg
+		return nil
+	})
+	jobFunc, ok := workers.Get("TypedClass")
+	if !ok {
+		t.Fatal("RegisterTyped: worker not registered")
+	}
+
+	job := &Job{Queue: "typed", raw: []byte(`{"class":"TypedClass","args":[{"id":7,"name":"c"}]}`)}
+	if err := jobFunc(context.Background(), job); err != nil {
+		t.Errorf("RegisterTyped: error %s", err)
+	}
+	if actual != (typedArg{ID: 7, Name: "c"}) {
+		t.Errorf("RegisterTyped: expected {7 c}, actual %v", actual)
+	}
+
+	job = &Job{Queue: "typed", raw: []byte(`{"class":"TypedClass","args":["oops"]}`)}
+	err := jobFunc(context.Background(), job)
+	var decodeError *DecodeError
+	if !errors.As(err, &decodeError) {
+		t.Errorf("RegisterTyped: expected *DecodeError, actual %v", err)
+	}
+	if name := exceptionName(err); name != "DecodeError" {
+		t.Errorf("RegisterTyped: expected exception DecodeError, actual %s", name)
+	}
+}
+
+func TestEncodeArgSymmetry(t *testing.T) {
+	expected := typedArg{ID: 9007199254740993, Name: "d"}
+	args, err := encodeArg(expected)
+	if err != nil {
+		t.Fatalf("encodeArg(%v): error %s", expected, err)
+	}
+
+	var actual typedArg
+	if err := decodeArg(&Job{Payload: Payload{Args: args}}, &actual); err != nil {
+		t.Fatalf("decodeArg(%v): error %s", args, err)
+	}
+	if actual != expected {
+		t.Errorf("encodeArg: expected %v, actual %v", expected, actual)
+	}
+}
@@ -0,0 +1,121 @@
+package goworker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// The policies for jobs whose class has no registered worker
+// function, set by -unknown-class.
+const (
+	// UnknownClassFail fails the job, as for an error.
+	UnknownClassFail = "fail"
+	// UnknownClassRequeue pushes the job back on the tail of
+	// its queue, for another process sharing the queue, such
+	// as a Ruby Resque worker, to work.
+	UnknownClassRequeue = "requeue"
+	// UnknownClassMove moves the job to the queue named by
+	// -unknown-class-queue.
+	UnknownClassMove = "move"
+)
+
+// unknownClass handles job, whose class has no registered
+// worker function, by the -unknown-class policy. A job is
+// failed rather than requeued more than
+// -unknown-class-requeues times, or moved to the queue it is
+// already on, so that it cannot circle forever. Each outcome
+// is counted in stat:unknown:failed, stat:unknown:requeued or
+// stat:unknown:moved.
+func (w *worker) unknownClass(conn *RedisConn, job *Job) {
+	message := fmt.Sprintf("No worker for %s in queue %s with args %v (job %s)", jobClass(job), job.Queue, job.Payload.Args, job.Payload.ID)
+
+	outcome := "failed"
+	switch workerSettings.UnknownClassPolicy {
+	case UnknownClassRequeue:
+		requeues := int(jsonNumber(job.Payload.Meta["unknown_class_requeues"]))
+		if requeues >= workerSettings.UnknownClassRequeues {
+			message = fmt.Sprintf("%s after requeueing it %d times", message, requeues)
+			break
+		}
+		setMeta(job, "unknown_class_requeues", requeues+1)
+		if err := repush(conn, job, job.Queue); err != nil {
+			logger.Criticalf("Error requeueing %v: %v", job.Payload.ID, err)
+			break
+		}
+		outcome = "requeued"
+	case UnknownClassMove:
+		queue := workerSettings.UnknownClassQueue
+		if queue == job.Queue {
+			message = fmt.Sprintf("%s, which is the -unknown-class-queue", message)
+			break
+		}
+		setMeta(job, "unknown_class_queue", job.Queue)
+		if err := repush(conn, job, queue); err != nil {
+			logger.Criticalf("Error moving %v to %s: %v", job.Payload.ID, queue, err)
+			break
+		}
+		outcome = "moved"
+	}
+
+	conn.Send("INCR", fmt.Sprintf("%sstat:unknown:%s", workerSettings.Namespace, outcome))
+	if outcome != "failed" {
+		if _, err := conn.flush(); err != nil {
+			logger.Criticalf("Error counting %v as %s: %v", job.Payload.ID, outcome, err)
+		}
+		logger.Infof("%s, %s", message, outcome)
+		return
+	}
+	logger.Critical(message)
+	w.finish(conn, job, errors.New(message))
+}
+
+// repush pushes job onto queue and waits for the push to
+// complete, so that the job is on its new queue before the
+// worker moves on.
+func repush(conn *RedisConn, job *Job, queue string) error {
+	buffer, err := rawWithMeta(job, queue)
+	if err != nil {
+		return err
+	}
+	command, args := pushCommand(queue, &job.Payload, buffer)
+	conn.Send(command, args...)
+	conn.Send("SADD", queuesKey(queue), queue)
+	_, err = conn.flush()
+	return err
+}
+
+// rawWithMeta returns the payload of job as it was read from
+// Redis with only its metadata, and the queue of a Sidekiq
+// job hash, replaced, so that numbers in its arguments keep
+// their precision. A job read without its payload, or moved
+// between a Resque and a Sidekiq queue, is encoded afresh.
+func rawWithMeta(job *Job, queue string) ([]byte, error) {
+	if job.raw == nil || isSidekiqQueue(queue) != isSidekiqQueue(job.Queue) {
+		return encodeJob(&Job{Queue: queue, Payload: job.Payload, sidekiq: job.sidekiq})
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(job.raw, &fields); err != nil {
+		return nil, err
+	}
+	meta, err := json.Marshal(job.Payload.Meta)
+	if err != nil {
+		return nil, err
+	}
+	fields["meta"] = meta
+	if isSidekiqQueue(queue) {
+		if fields["queue"], err = json.Marshal(queue); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// setMeta sets the metadata key of job to value.
+func setMeta(job *Job, key string, value interface{}) {
+	if job.Payload.Meta == nil {
+		job.Payload.Meta = make(map[string]interface{})
+	}
+	job.Payload.Meta[key] = value
+}
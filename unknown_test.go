@@ -0,0 +1,88 @@
+package goworker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var unknownClassTests = []struct {
+	policy   string
+	queue    string
+	requeues interface{}
+	outcome  string
+	onQueue  string
+	failed   int
+}{
+	{UnknownClassFail, "", nil, "failed", "", 1},
+	{UnknownClassRequeue, "", nil, "requeued", "unknownQueue", 0},
+	{UnknownClassRequeue, "", 2.0, "requeued", "unknownQueue", 0},
+	{UnknownClassRequeue, "", 3.0, "failed", "", 1},
+	{UnknownClassMove, "foreignQueue", nil, "moved", "foreignQueue", 0},
+	{UnknownClassMove, "unknownQueue", nil, "failed", "", 1},
+}
+
+func TestUnknownClass(t *testing.T) {
+	workerSettings.Queues = []string{"unknownQueue"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+	defer func() {
+		workerSettings.UnknownClassPolicy = UnknownClassFail
+		workerSettings.UnknownClassQueue = ""
+		workerSettings.UnknownClassRequeues = 25
+	}()
+	workerSettings.UnknownClassRequeues = 3
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+
+	w, err := newWorker("1", workerSettings.Queues)
+	if err != nil {
+		t.Fatalf("newWorker: error %s", err)
+	}
+
+	for _, tt := range unknownClassTests {
+		workerSettings.UnknownClassPolicy = tt.policy
+		workerSettings.UnknownClassQueue = tt.queue
+		failedKey := fmt.Sprintf("%sfailed", workerSettings.Namespace)
+		outcomeKey := fmt.Sprintf("%sstat:unknown:%s", workerSettings.Namespace, tt.outcome)
+		conn.Do("DEL", queueKey("unknownQueue"), queueKey("foreignQueue"), failedKey, outcomeKey)
+
+		job := &Job{Queue: "unknownQueue", Payload: Payload{Class: "RubyOnly", Args: []interface{}{9007199254740993.0}, ID: "unknown"}}
+		job.raw = []byte(`{"class":"RubyOnly","args":[9007199254740993],"id":"unknown"}`)
+		if tt.requeues != nil {
+			job.Payload.Meta = map[string]interface{}{"unknown_class_requeues": tt.requeues}
+		}
+		w.unknownClass(conn, job)
+
+		if count, _ := redis.Int(conn.Do("GET", outcomeKey)); count != 1 {
+			t.Errorf("unknownClass(%s %v): expected %s to be 1, actual %d", tt.policy, tt.requeues, outcomeKey, count)
+		}
+		if failed, _ := redis.Int(conn.Do("LLEN", failedKey)); failed != tt.failed {
+			t.Errorf("unknownClass(%s %v): expected %d failed, actual %d", tt.policy, tt.requeues, tt.failed, failed)
+		}
+		for _, queue := range []string{"unknownQueue", "foreignQueue"} {
+			popped := popTestJob(t, conn, queue)
+			if queue != tt.onQueue {
+				if popped != nil {
+					t.Errorf("unknownClass(%s %v): expected nothing on %s, actual %+v", tt.policy, tt.requeues, queue, popped.Payload)
+				}
+				continue
+			}
+			if popped == nil || popped.Payload.ID != "unknown" {
+				t.Errorf("unknownClass(%s %v): expected the job on %s, actual %+v", tt.policy, tt.requeues, queue, popped)
+				continue
+			}
+			if !strings.Contains(string(popped.raw), `"args":[9007199254740993]`) {
+				t.Errorf("unknownClass(%s %v): expected the args pushed unchanged, actual %s", tt.policy, tt.requeues, popped.raw)
+			}
+		}
+	}
+}
@@ -39,17 +39,38 @@ func (w *worker) start(conn *RedisConn, job *Job) error {
 	}
 
 	conn.Send("SET", fmt.Sprintf("%sworker:%s", workerSettings.Namespace, w), buffer)
-	logger.Debugf("Processing %s since %s [%v]", work.Queue, work.RunAt, work.Payload.Class)
+	logger.Debugf("Processing %s since %s [%v %s attempt %d]", work.Queue, work.RunAt, work.Payload.Class, work.Payload.ID, work.Payload.Attempt)
 
 	return w.process.start(conn)
 }
 
 func (w *worker) fail(conn *RedisConn, job *Job, err error) error {
+	if errors.Is(err, ErrJobKilled) {
+		// The job stopped because it was asked to, so it is
+		// not failed, only marked killed in its status.
+		if err := failWorkflow(conn, job); err != nil {
+			logger.Criticalf("Error recording kill of %v in workflow: %v", job.Payload.ID, err)
+		}
+		return nil
+	}
+
+	poisoned := errors.Is(err, ErrPoisonJob)
+	if isSidekiqQueue(job.Queue) && !poisoned {
+		if errSidekiq := failSidekiq(conn, job, err); errSidekiq != nil {
+			logger.Criticalf("Error recording failure of %v for Sidekiq: %v", job.Payload.ID, errSidekiq)
+		}
+		if err := failWorkflow(conn, job); err != nil {
+			logger.Criticalf("Error recording failure of %v in workflow: %v", job.Payload.ID, err)
+		}
+		return w.process.fail(conn)
+	}
+
 	failure := &failure{
 		FailedAt:  time.Now(),
 		Payload:   job.Payload,
-		Exception: "Error",
+		Exception: exceptionName(err),
 		Error:     err.Error(),
+		Backtrace: backtrace(err),
 		Worker:    w,
 		Queue:     job.Queue,
 	}
@@ -57,7 +78,16 @@ func (w *worker) fail(conn *RedisConn, job *Job, err error) error {
 	if err != nil {
 		return err
 	}
-	conn.Send("RPUSH", fmt.Sprintf("%sfailed", workerSettings.Namespace), buffer)
+	if poisoned {
+		conn.Send("RPUSH", fmt.Sprintf("%squarantine", workerSettings.Namespace), buffer)
+		conn.Send("INCR", fmt.Sprintf("%sstat:quarantined", workerSettings.Namespace))
+	} else {
+		conn.Send("RPUSH", fmt.Sprintf("%sfailed", workerSettings.Namespace), buffer)
+	}
+
+	if err := failWorkflow(conn, job); err != nil {
+		logger.Criticalf("Error recording failure of %v in workflow: %v", job.Payload.ID, err)
+	}
 
 	return w.process.fail(conn)
 }
@@ -66,6 +96,10 @@ func (w *worker) succeed(conn *RedisConn, job *Job) error {
 	conn.Send("INCR", fmt.Sprintf("%sstat:processed", workerSettings.Namespace))
 	conn.Send("INCR", fmt.Sprintf("%sstat:processed:%s", workerSettings.Namespace, w))
 
+	if err := advanceWorkflow(conn, job); err != nil {
+		logger.Criticalf("Error advancing workflow of %v: %v", job.Payload.ID, err)
+	}
+
 	return nil
 }
 
@@ -75,14 +109,23 @@ func (w *worker) finish(conn *RedisConn, job *Job, err error) error {
 	} else {
 		w.succeed(conn, job)
 	}
+	if errStart := finishStart(conn, job); errStart != nil {
+		logger.Criticalf("Error clearing starts of %v: %v", job.Payload.ID, errStart)
+	}
+	if errStatus := finishStatus(conn, job, err); errStatus != nil {
+		logger.Criticalf("Error updating status of %v: %v", job.Payload.ID, errStatus)
+	}
+	if errBatch := finishBatch(conn, job, err); errBatch != nil {
+		logger.Criticalf("Error recording %v in batch: %v", job.Payload.ID, errBatch)
+	}
 	return w.process.finish(conn)
 }
 
-func (w *worker) work(jobs <-chan *Job, monitor *sync.WaitGroup) {
+func (w *worker) work(jobs <-chan *Job, stop <-chan struct{}, monitor *sync.WaitGroup) error {
 	conn, err := GetConn()
 	if err != nil {
 		logger.Criticalf("Error on getting connection in worker %v: %v", w, err)
-		return
+		return err
 	} else {
 		w.open(conn)
 		PutConn(conn)
@@ -103,53 +146,77 @@ func (w *worker) work(jobs <-chan *Job, monitor *sync.WaitGroup) {
 				PutConn(conn)
 			}
 		}()
-		for job := range jobs {
-			if workerFunc, ok := workers.Get(job.Payload.Class); ok {
-				w.run(job, workerFunc)
+		for {
+			var job *Job
+			var ok bool
+			select {
+			case <-stop:
+				return
+			case job, ok = <-jobs:
+				if !ok {
+					return
+				}
+			}
 
-				logger.Debugf("done: (Job{%s} | %s | %v)", job.Queue, job.Payload.Class, job.Payload.Args)
-			} else {
-				errorLog := fmt.Sprintf("No worker for %s in queue %s with args %v", job.Payload.Class, job.Queue, job.Payload.Args)
-				logger.Critical(errorLog)
+			if jobFunc, ok := lookupJobFunc(job); ok {
+				w.run(job, jobFunc)
 
-				conn, err := GetConn()
+				logger.Debugf("done: (Job{%s} | %s | %s | %v)", job.Queue, job.Payload.Class, job.Payload.ID, job.Payload.Args)
+			} else {
+				conn, err := getConnRetrying()
 				if err != nil {
-					logger.Criticalf("Error on getting connection in worker %v: %v", w, err)
-					return
+					logger.Criticalf("Error on getting connection in worker %v, losing %v: %v", w, job, err)
 				} else {
-					w.finish(conn, job, errors.New(errorLog))
+					w.unknownClass(conn, job)
 					PutConn(conn)
 				}
 			}
 		}
 	}()
+
+	return nil
 }
 
-func (w *worker) run(job *Job, workerFunc workerFunc) {
+func (w *worker) run(job *Job, jobFunc jobFunc) {
 	var err error
+	tasks := &subtasks{}
 	defer func() {
-		conn, errCon := GetConn()
+		conn, errCon := getConnRetrying()
 		if errCon != nil {
 			logger.Criticalf("Error on getting connection in worker on finish %v: %v", w, errCon)
 			return
 		} else {
+			// PutConn waits for the replies, so the job is
+			// recorded as finished before run returns.
 			w.finish(conn, job, err)
 			PutConn(conn)
 		}
 	}()
 	defer func() {
 		if r := recover(); r != nil {
-			err = errors.New(fmt.Sprint(r))
+			err = newPanicError(r)
+		}
+		if errTasks := tasks.wait(); err == nil {
+			err = errTasks
 		}
 	}()
 
-	conn, err := GetConn()
+	job.Payload.Attempt++
+
+	conn, err := getConnRetrying()
 	if err != nil {
 		logger.Criticalf("Error on getting connection in worker on start %v: %v", w, err)
 		return
 	} else {
 		w.start(conn, job)
+		err = countStart(conn, job)
+		if err == nil {
+			err = startStatus(conn, job)
+		}
 		PutConn(conn)
+		if err != nil {
+			return
+		}
 	}
-	err = workerFunc(job.Queue, job.Payload.Args...)
+	err = jobFunc(newSubtasksContext(newJobContext(job), tasks), job)
 }
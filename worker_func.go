@@ -1,3 +1,12 @@
 package goworker
 
+import (
+	"golang.org/x/net/context"
+)
+
 type workerFunc func(string, ...interface{}) error
+
+// jobFunc is the form every registered worker function is
+// stored and run in, whatever signature it was registered
+// with.
+type jobFunc func(context.Context, *Job) error
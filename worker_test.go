@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+
+	"golang.org/x/net/context"
 )
 
 var workerMarshalJSONTests = []struct {
@@ -98,3 +100,38 @@ func TestRegister(t *testing.T) {
 		}
 	})
 }
+
+func TestRegisterContext(t *testing.T) {
+	workerSettings.Queues = []string{"contextQueue"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+	defer func() {
+		workers.Lock()
+		delete(workers.workers, "ContextClass")
+		workers.Unlock()
+	}()
+
+	var actual *Job
+	var actualArgs []interface{}
+	RegisterContext("ContextClass", func(ctx context.Context, queue string, args ...interface{}) error {
+		actual, _ = JobFromContext(ctx)
+		actualArgs = args
+		return nil
+	})
+	jobFunc, ok := workers.Get("ContextClass")
+	if !ok {
+		t.Fatalf("RegisterContext: expected ContextClass to be registered")
+	}
+
+	job := &Job{Queue: "contextQueue", Payload: Payload{Class: "ContextClass", Args: []interface{}{"arg"}, ID: "context"}}
+	w := &worker{}
+	w.run(job, jobFunc)
+	if actual != job || actual.Payload.Attempt != 1 {
+		t.Errorf("RegisterContext: expected job %+v in context, actual %+v", job, actual)
+	}
+	if fmt.Sprint(actualArgs) != "[arg]" {
+		t.Errorf("RegisterContext: expected args [arg], actual %v", actualArgs)
+	}
+}
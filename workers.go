@@ -1,24 +1,25 @@
 package goworker
 
 import (
-	"encoding/json"
-	"fmt"
 	"sync"
+
+	"golang.org/x/net/context"
 )
 
 type workersMutex struct {
 	sync.RWMutex
-	workers map[string]workerFunc
+	workers map[string]jobFunc
+	queues  map[string]string
 }
 
-func (wm *workersMutex) Add(class string, worker workerFunc) {
+func (wm *workersMutex) Add(class string, worker jobFunc) {
 	wm.Lock()
 	defer wm.Unlock()
 
 	wm.workers[class] = worker
 }
 
-func (wm *workersMutex) Get(class string) (worker workerFunc, ok bool) {
+func (wm *workersMutex) Get(class string) (worker jobFunc, ok bool) {
 	wm.RLock()
 	defer wm.RUnlock()
 
@@ -26,12 +27,30 @@ func (wm *workersMutex) Get(class string) (worker workerFunc, ok bool) {
 	return
 }
 
+// SetQueue records queue as the default queue of class.
+func (wm *workersMutex) SetQueue(class string, queue string) {
+	wm.Lock()
+	defer wm.Unlock()
+
+	wm.queues[class] = queue
+}
+
+// Queue returns the default queue of class.
+func (wm *workersMutex) Queue(class string) (queue string, ok bool) {
+	wm.RLock()
+	defer wm.RUnlock()
+
+	queue, ok = wm.queues[class]
+	return
+}
+
 var workers *workersMutex
 
 func init() {
 	workers = &workersMutex{
 		RWMutex: sync.RWMutex{},
-		workers: make(map[string]workerFunc),
+		workers: make(map[string]jobFunc),
+		queues:  make(map[string]string),
 	}
 }
 
@@ -40,9 +59,23 @@ func init() {
 // job. Worker is a function which accepts a queue and an
 // arbitrary array of interfaces as arguments.
 func Register(class string, worker workerFunc) {
-	workers.Add(class, worker)
+	RegisterContext(class, func(ctx context.Context, queue string, args ...interface{}) error {
+		return worker(queue, args...)
+	})
+}
+
+// RegisterContext registers a worker function like Register,
+// but one that also receives the context of the job, from
+// which it can read the job with JobFromContext.
+func RegisterContext(class string, worker func(ctx context.Context, queue string, args ...interface{}) error) {
+	workers.Add(class, func(ctx context.Context, job *Job) error {
+		return worker(ctx, job.Queue, job.Payload.Args...)
+	})
 }
 
+// Enqueue pushes job onto its queue and waits for Redis to
+// confirm it. The payload is given an ID and enqueued time
+// unless it already has them.
 func Enqueue(job *Job) error {
 	err := Init()
 	if err != nil {
@@ -56,23 +89,40 @@ func Enqueue(job *Job) error {
 	}
 	defer PutConn(conn)
 
-	buffer, err := json.Marshal(job.Payload)
+	if err := enqueue(conn, job); err != nil {
+		return err
+	}
+
+	_, err = conn.flush()
+	return err
+}
+
+// enqueue sends the commands that push job onto its queue
+// over conn, without waiting for their replies.
+func enqueue(conn *RedisConn, job *Job) error {
+	if err := job.Payload.stamp(); err != nil {
+		logger.Criticalf("Cant generate job ID on enqueue")
+		return err
+	}
+
+	buffer, err := encodeJob(job)
 	if err != nil {
 		logger.Criticalf("Cant marshal payload on enqueue")
 		return err
 	}
 
-	err = conn.Send("RPUSH", fmt.Sprintf("%squeue:%s", workerSettings.Namespace, job.Queue), buffer)
+	command, args := pushCommand(job.Queue, &job.Payload, buffer)
+	err = conn.Send(command, args...)
 	if err != nil {
 		logger.Criticalf("Cant push to queue")
 		return err
 	}
 
-	err = conn.Send("SADD", fmt.Sprintf("%squeues", workerSettings.Namespace), job.Queue)
+	err = conn.Send("SADD", queuesKey(job.Queue), job.Queue)
 	if err != nil {
 		logger.Criticalf("Cant register queue to list of use queues")
 		return err
 	}
 
-	return conn.Flush()
+	return nil
 }
@@ -0,0 +1,285 @@
+package goworker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	errorEmptyWorkflow    = errors.New("a workflow needs at least one job")
+	errorWorkflowNotFound = errors.New("no workflow with that ID")
+)
+
+// workflowTTL is how long workflow state is kept in Redis
+// after it was last advanced.
+const workflowTTL = 7 * 24 * time.Hour
+
+// Workflow states reported by WorkflowStatus.
+const (
+	WorkflowRunning   = "running"
+	WorkflowSucceeded = "succeeded"
+	WorkflowFailed    = "failed"
+)
+
+// advanceWorkflowScript records that a job of the current
+// stage of a workflow succeeded. A job is counted once,
+// however many times it runs. When it was the last one, the
+// workflow moves to its next stage and pushes its jobs in
+// the same script, so they are enqueued exactly once, or
+// succeeds if there is none.
+var advanceWorkflowScript = redis.NewScript(3, pushStoredJobLua+`
+local stage = tonumber(redis.call('HGET', KEYS[1], 'stage'))
+if stage == nil or stage ~= tonumber(ARGV[1]) then
+	return 0
+end
+if redis.call('SREM', KEYS[3], ARGV[2]) == 0 then
+	return 0
+end
+if redis.call('HINCRBY', KEYS[1], 'pending', -1) > 0 then
+	return 0
+end
+stage = stage + 1
+redis.call('HSET', KEYS[1], 'stage', stage)
+redis.call('HSET', KEYS[1], 'failed', 0)
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+redis.call('EXPIRE', KEYS[2], ARGV[3])
+local jobs = redis.call('LINDEX', KEYS[2], stage)
+if not jobs then
+	redis.call('HSET', KEYS[1], 'status', 'succeeded')
+	return 0
+end
+jobs = cjson.decode(jobs)
+for _, job in ipairs(jobs) do
+	pushStoredJob(job, ARGV[4])
+	redis.call('SADD', KEYS[3], job.id)
+end
+redis.call('EXPIRE', KEYS[3], ARGV[3])
+redis.call('HSET', KEYS[1], 'status', 'running')
+redis.call('HSET', KEYS[1], 'pending', #jobs)
+return #jobs
+`)
+
+// failWorkflowScript records that a job of the current stage
+// of a workflow failed.
+var failWorkflowScript = redis.NewScript(2, `
+local stage = tonumber(redis.call('HGET', KEYS[1], 'stage'))
+if stage == nil or stage ~= tonumber(ARGV[1]) then
+	return false
+end
+if redis.call('SISMEMBER', KEYS[2], ARGV[2]) == 0 then
+	return false
+end
+redis.call('HINCRBY', KEYS[1], 'failed', 1)
+redis.call('HSET', KEYS[1], 'status', 'failed')
+return true
+`)
+
+// Workflow runs jobs in stages. Each stage is a single job
+// or a group of jobs run in parallel, and a stage is
+// enqueued only once every job of the previous stage has
+// succeeded. Progress is kept in Redis and advanced by the
+// worker that finishes the last job of a stage, so a job
+// that fails and is retried from the failed list continues
+// the workflow when it succeeds.
+//
+//	id, err := goworker.NewWorkflow().
+//		Then(fetch).
+//		Group(resizeSmall, resizeLarge).
+//		Then(notify).
+//		Enqueue()
+type Workflow struct {
+	stages [][]*Job
+}
+
+// WorkflowState is the progress of a workflow.
+type WorkflowState struct {
+	ID      string
+	Status  string
+	Stage   int
+	Stages  int
+	Pending int
+	Failed  int
+}
+
+// NewWorkflow returns an empty workflow.
+func NewWorkflow() *Workflow {
+	return &Workflow{}
+}
+
+// Then adds a stage that runs job after the previous stage
+// has succeeded.
+func (wf *Workflow) Then(job *Job) *Workflow {
+	return wf.Group(job)
+}
+
+// Group adds a stage that runs jobs in parallel after the
+// previous stage has succeeded. The next stage runs once
+// all of them have succeeded.
+func (wf *Workflow) Group(jobs ...*Job) *Workflow {
+	if len(jobs) > 0 {
+		wf.stages = append(wf.stages, jobs)
+	}
+	return wf
+}
+
+// Enqueue stores the workflow in Redis, enqueues the jobs of
+// its first stage, and returns the workflow ID.
+func (wf *Workflow) Enqueue() (string, error) {
+	if len(wf.stages) == 0 {
+		return "", errorEmptyWorkflow
+	}
+
+	if err := Init(); err != nil {
+		return "", err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return "", err
+	}
+
+	stages := make([]interface{}, 0, len(wf.stages)+1)
+	stages = append(stages, workflowStagesKey(id))
+	pending := []interface{}{workflowPendingKey(id)}
+	for i, jobs := range wf.stages {
+		stage := make([]*storedJob, len(jobs))
+		for j, job := range jobs {
+			if job.Payload.Meta == nil {
+				job.Payload.Meta = make(map[string]interface{})
+			}
+			job.Payload.Meta["workflow_id"] = id
+			job.Payload.Meta["workflow_stage"] = i
+			if stage[j], err = newStoredJob(job); err != nil {
+				return "", err
+			}
+			if i == 0 {
+				pending = append(pending, job.Payload.ID)
+			}
+		}
+		buffer, err := json.Marshal(stage)
+		if err != nil {
+			return "", err
+		}
+		stages = append(stages, buffer)
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		logger.Criticalf("Error on getting connection on workflow enqueue")
+		return "", err
+	}
+	defer PutConn(conn)
+
+	ttl := int(workflowTTL / time.Second)
+	conn.Send("HSET", workflowKey(id), "status", WorkflowRunning, "stage", 0, "stages", len(wf.stages), "pending", len(wf.stages[0]), "failed", 0)
+	conn.Send("EXPIRE", workflowKey(id), ttl)
+	conn.Send("RPUSH", stages...)
+	conn.Send("EXPIRE", workflowStagesKey(id), ttl)
+	conn.Send("SADD", pending...)
+	conn.Send("EXPIRE", workflowPendingKey(id), ttl)
+	for _, job := range wf.stages[0] {
+		if err := enqueue(conn, job); err != nil {
+			return "", err
+		}
+	}
+	if _, err := conn.Do(""); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// WorkflowStatus returns the progress of the workflow with
+// the given ID.
+func WorkflowStatus(id string) (*WorkflowState, error) {
+	if err := Init(); err != nil {
+		return nil, err
+	}
+
+	conn, err := GetConn()
+	if err != nil {
+		return nil, err
+	}
+	defer PutConn(conn)
+
+	values, err := redis.StringMap(conn.Do("HGETALL", workflowKey(id)))
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, errorWorkflowNotFound
+	}
+
+	state := &WorkflowState{
+		ID:     id,
+		Status: values["status"],
+	}
+	fmt.Sscan(values["stage"], &state.Stage)
+	fmt.Sscan(values["stages"], &state.Stages)
+	fmt.Sscan(values["pending"], &state.Pending)
+	fmt.Sscan(values["failed"], &state.Failed)
+	if state.Status == WorkflowSucceeded {
+		state.Pending = 0
+	}
+	return state, nil
+}
+
+// workflowOf returns the workflow ID and stage of job, if it
+// belongs to a workflow.
+func workflowOf(job *Job) (id string, stage string, ok bool) {
+	id, ok = job.Payload.Meta["workflow_id"].(string)
+	if !ok {
+		return "", "", false
+	}
+	return id, fmt.Sprint(job.Payload.Meta["workflow_stage"]), true
+}
+
+// advanceWorkflow records the success of job in its
+// workflow, enqueueing the next stage if the job completed
+// the current one.
+func advanceWorkflow(conn *RedisConn, job *Job) error {
+	id, stage, ok := workflowOf(job)
+	if !ok {
+		return nil
+	}
+
+	enqueued, err := redis.Int(conn.runScript(advanceWorkflowScript, workflowKey(id), workflowStagesKey(id), workflowPendingKey(id), stage, job.Payload.ID, int(workflowTTL/time.Second), fmt.Sprintf("%squeues", workerSettings.Namespace)))
+	if err != nil {
+		return err
+	}
+	if enqueued > 0 {
+		logger.Infof("Workflow %s finished stage %s, enqueued %d jobs", id, stage, enqueued)
+	}
+	return nil
+}
+
+// failWorkflow records the failure of job in its workflow.
+func failWorkflow(conn *RedisConn, job *Job) error {
+	id, stage, ok := workflowOf(job)
+	if !ok {
+		return nil
+	}
+
+	_, err := conn.runScript(failWorkflowScript, workflowKey(id), workflowPendingKey(id), stage, job.Payload.ID)
+	if err == redis.ErrNil {
+		return nil
+	}
+	return err
+}
+
+func workflowKey(id string) string {
+	return fmt.Sprintf("%sworkflow:%s", workerSettings.Namespace, id)
+}
+
+func workflowStagesKey(id string) string {
+	return fmt.Sprintf("%sworkflow:%s:stages", workerSettings.Namespace, id)
+}
+
+// workflowPendingKey is the set of the IDs of the jobs of
+// the current stage that have not yet succeeded.
+func workflowPendingKey(id string) string {
+	return fmt.Sprintf("%sworkflow:%s:pending", workerSettings.Namespace, id)
+}
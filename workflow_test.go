@@ -0,0 +1,112 @@
+package goworker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// popTestJob pops and decodes the next job from queue as the
+// poller would.
+func popTestJob(t *testing.T, conn *RedisConn, queue string) *Job {
+	reply, err := popJob(conn, queue)
+	if err != nil {
+		t.Fatalf("popJob: error %s", err)
+	}
+	if reply == nil {
+		return nil
+	}
+	job := &Job{Queue: queue, raw: reply.([]byte)}
+	decoder := json.NewDecoder(bytes.NewReader(job.raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&job.Payload); err != nil {
+		t.Fatalf("Decode: error %s", err)
+	}
+	return job
+}
+
+func TestWorkflow(t *testing.T) {
+	workerSettings.Queues = []string{"workflowQueue"}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: error %s", err)
+	}
+	defer Close()
+
+	conn, err := GetConn()
+	if err != nil {
+		t.Fatalf("GetConn: error %s", err)
+	}
+	defer PutConn(conn)
+	conn.Do("DEL", queueKey("workflowQueue"))
+
+	step := func(class string) *Job {
+		return &Job{Queue: "workflowQueue", Payload: Payload{Class: class, Args: []interface{}{}}}
+	}
+	id, err := NewWorkflow().Then(step("A")).Group(step("B1"), step("B2")).Then(step("C")).Enqueue()
+	if err != nil {
+		t.Fatalf("Workflow: enqueue error %s", err)
+	}
+
+	w := &worker{}
+	expectStatus := func(status string, stage, pending int) {
+		t.Helper()
+		state, err := WorkflowStatus(id)
+		if err != nil {
+			t.Fatalf("WorkflowStatus: error %s", err)
+		}
+		if state.Status != status || state.Stage != stage || state.Pending != pending {
+			t.Errorf("WorkflowStatus: expected %s stage %d pending %d, actual %+v", status, stage, pending, state)
+		}
+	}
+	expectStatus(WorkflowRunning, 0, 1)
+
+	a := popTestJob(t, conn, "workflowQueue")
+	if a == nil || a.Payload.Class != "A" || popTestJob(t, conn, "workflowQueue") != nil {
+		t.Fatalf("Workflow: expected only A to be enqueued, actual %+v", a)
+	}
+	w.succeed(conn, a)
+	conn.Do("")
+	expectStatus(WorkflowRunning, 1, 2)
+
+	b1 := popTestJob(t, conn, "workflowQueue")
+	b2 := popTestJob(t, conn, "workflowQueue")
+	if b1 == nil || b2 == nil || popTestJob(t, conn, "workflowQueue") != nil {
+		t.Fatalf("Workflow: expected B1 and B2 to be enqueued, actual %+v %+v", b1, b2)
+	}
+
+	w.fail(conn, b1, errors.New("b1 failed"))
+	w.succeed(conn, b2)
+	conn.Do("")
+	expectStatus(WorkflowFailed, 1, 1)
+
+	// Replaying A, or B2 while B1 has yet to succeed, must
+	// not advance the workflow.
+	w.succeed(conn, a)
+	w.succeed(conn, b2)
+	w.fail(conn, b2, errors.New("b2 replayed"))
+	conn.Do("")
+	expectStatus(WorkflowFailed, 1, 1)
+	if job := popTestJob(t, conn, "workflowQueue"); job != nil {
+		t.Fatalf("Workflow: expected no job before B1 succeeds, actual %+v", job)
+	}
+
+	w.succeed(conn, b1)
+	conn.Do("")
+	expectStatus(WorkflowRunning, 2, 1)
+
+	c := popTestJob(t, conn, "workflowQueue")
+	if c == nil || c.Payload.Class != "C" {
+		t.Fatalf("Workflow: expected C to be enqueued, actual %+v", c)
+	}
+	w.succeed(conn, c)
+	conn.Do("")
+	expectStatus(WorkflowSucceeded, 3, 0)
+
+	if _, err := WorkflowStatus("missing"); err != errorWorkflowNotFound {
+		t.Errorf("WorkflowStatus: expected err %v, actual err %v", errorWorkflowNotFound, err)
+	}
+	if _, err := NewWorkflow().Enqueue(); err != errorEmptyWorkflow {
+		t.Errorf("Workflow: expected err %v, actual err %v", errorEmptyWorkflow, err)
+	}
+}